@@ -3,6 +3,7 @@ package utils
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"math"
@@ -42,6 +43,12 @@ var (
 	spotifyHttpPlaylistRegex = `^(https:\/\/open\.spotify\.com\/playlist\/[[a-zA-Z0-9]{22}\?.*)$`
 	spotifyHttpAlbumRegex    = `^(https:\/\/open\.spotify\.com\/album\/[[a-zA-Z0-9]{22}\?.*)$`
 	spotifyHttpTrackRegex    = `^(https:\/\/open\.spotify\.com\/track\/[[a-zA-Z0-9]{22}\?.*)$`
+
+	soundcloudUrlRegex = `^(?:https?:\/\/)?(?:www\.|m\.)?soundcloud\.com\/[a-zA-Z0-9\-_]+\/(?:sets\/)?[a-zA-Z0-9\-_]+\/?(?:\?.*)?$`
+	soundcloudSetRegex = `^(?:https?:\/\/)?(?:www\.|m\.)?soundcloud\.com\/[a-zA-Z0-9\-_]+\/sets\/[a-zA-Z0-9\-_]+\/?(?:\?.*)?$`
+
+	bandcampUrlRegex   = `^(?:https?:\/\/)?[a-zA-Z0-9\-]+\.bandcamp\.com\/(?:track|album)\/[a-zA-Z0-9\-_]+\/?(?:\?.*)?$`
+	bandcampAlbumRegex = `^(?:https?:\/\/)?[a-zA-Z0-9\-]+\.bandcamp\.com\/album\/[a-zA-Z0-9\-_]+\/?(?:\?.*)?$`
 )
 
 func zeroPad(str string) (result string) {
@@ -68,6 +75,23 @@ func SecondsToHuman(input float64) (result string) {
 	return
 }
 
+// ParseTimestamp parses a timestamp in the form ss, mm:ss or hh:mm:ss
+func ParseTimestamp(timestamp string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(timestamp), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %s", timestamp)
+	}
+	var seconds int
+	for _, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("invalid timestamp %s", timestamp)
+		}
+		seconds = seconds*60 + value
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
 func FormatVideoTitle(videoTitle string) string {
 	newTitle := strings.TrimSpace(videoTitle)
 
@@ -114,6 +138,30 @@ func IsSpotifyPlaylistUrl(url string) bool {
 	return re.MatchString(url)
 }
 
+// IsSoundcloudUrl returns whether the url is a soundcloud track or set
+func IsSoundcloudUrl(url string) bool {
+	re := regexp.MustCompile(soundcloudUrlRegex)
+	return re.MatchString(url)
+}
+
+// IsSoundcloudSetUrl returns whether the url is a soundcloud set
+func IsSoundcloudSetUrl(url string) bool {
+	re := regexp.MustCompile(soundcloudSetRegex)
+	return re.MatchString(url)
+}
+
+// IsBandcampUrl returns whether the url is a bandcamp track or album
+func IsBandcampUrl(url string) bool {
+	re := regexp.MustCompile(bandcampUrlRegex)
+	return re.MatchString(url)
+}
+
+// IsBandcampAlbumUrl returns whether the url is a bandcamp album
+func IsBandcampAlbumUrl(url string) bool {
+	re := regexp.MustCompile(bandcampAlbumRegex)
+	return re.MatchString(url)
+}
+
 func GetSpotifyID(url string) string {
 	re := regexp.MustCompile(spotifyHttpUrlRegex)
 	matches := re.FindStringSubmatch(url)
@@ -228,19 +276,23 @@ func GetRole(state *discordgo.State, guildID, roleID string) (string, error) {
 	return role.Name, nil
 }
 
-// PrintHelp print the avalible command for the bot
-func PrintHelp(s *discordgo.Session, channel string) {
-	_, err := s.ChannelMessageSend(channel,
-		"Avalible commands: \n"+
-			"**help**: Show this command\n"+
-			"**ping**: Respods with pong!\n"+
-			"**version**: Responds with bot version\n"+
-			"**chuck**: Responds with chuck norris joke\n"+
-			"**play**: Play a youtube link\n"+
-			"**stop**: Stop playing music\n"+
-			"**queue**: Show the queue of music\n"+
-			"**shuffle**: Shuffle the songs in the queue")
-	if err != nil {
-		log.Println("error sending message,", err)
+// CommandHelp describes a command in the help message
+type CommandHelp struct {
+	Name        string
+	Usage       string
+	Description string
+}
+
+// HelpMessage returns the help message for the given commands
+func HelpMessage(commands []CommandHelp) string {
+	var help strings.Builder
+	help.WriteString("Avalible commands: ")
+	for _, command := range commands {
+		help.WriteString("\n**" + command.Name + "**")
+		if command.Usage != "" {
+			help.WriteString(" " + command.Usage)
+		}
+		help.WriteString(": " + command.Description)
 	}
+	return help.String()
 }
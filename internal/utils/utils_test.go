@@ -3,44 +3,136 @@ package utils
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/sajfer/discordgo"
 )
 
-func TestPrintHelp(t *testing.T) {
+func TestGetChannel(t *testing.T) {
 	type args struct {
-		s       *discordgo.Session
-		channel string
+		server *discordgo.Guild
+		name   string
 	}
 	tests := []struct {
 		name string
 		args args
+		want *discordgo.Channel
 	}{
 		// TODO: Add test cases.
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			PrintHelp(tt.args.s, tt.args.channel)
+			if got := GetChannel(tt.args.server, tt.args.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetChannel() = %v, want %v", got, tt.want)
+			}
 		})
 	}
 }
 
-func TestGetChannel(t *testing.T) {
-	type args struct {
-		server *discordgo.Guild
-		name   string
+func TestHelpMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []CommandHelp
+		want     string
+	}{
+		{
+			name:     "no commands",
+			commands: nil,
+			want:     "Avalible commands: ",
+		},
+		{
+			name: "with and without usage",
+			commands: []CommandHelp{
+				{Name: "ping", Description: "Respods with pong!"},
+				{Name: "play", Usage: "<link|query>", Description: "Play a song"},
+			},
+			want: "Avalible commands: \n**ping**: Respods with pong!\n**play** <link|query>: Play a song",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HelpMessage(tt.commands); got != tt.want {
+				t.Errorf("HelpMessage() = %q, want %q", got, tt.want)
+			}
+		})
 	}
+}
+
+func TestParseTimestamp(t *testing.T) {
 	tests := []struct {
-		name string
-		args args
-		want *discordgo.Channel
+		name      string
+		timestamp string
+		want      time.Duration
+		wantErr   bool
 	}{
-		// TODO: Add test cases.
+		{name: "seconds", timestamp: "90", want: 90 * time.Second},
+		{name: "minutes", timestamp: "01:30", want: 90 * time.Second},
+		{name: "hours", timestamp: "1:02:03", want: time.Hour + 2*time.Minute + 3*time.Second},
+		{name: "empty", timestamp: "", wantErr: true},
+		{name: "negative", timestamp: "-1:00", wantErr: true},
+		{name: "too many parts", timestamp: "1:2:3:4", wantErr: true},
+		{name: "not a number", timestamp: "ab:cd", wantErr: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := GetChannel(tt.args.server, tt.args.name); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("GetChannel() = %v, want %v", got, tt.want)
+			got, err := ParseTimestamp(tt.timestamp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimestamp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTimestamp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSoundcloudUrl(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantUrl bool
+		wantSet bool
+	}{
+		{url: "https://soundcloud.com/artist/track-name", wantUrl: true},
+		{url: "https://soundcloud.com/artist/track-name?si=abc&utm_source=clipboard", wantUrl: true},
+		{url: "soundcloud.com/artist/track_name/", wantUrl: true},
+		{url: "https://m.soundcloud.com/artist/track-name", wantUrl: true},
+		{url: "https://soundcloud.com/artist/sets/set-name", wantUrl: true, wantSet: true},
+		{url: "https://soundcloud.com/artist", wantUrl: false},
+		{url: "https://soundcloud.com/artist/track/comments", wantUrl: false},
+		{url: "https://example.com/artist/track-name", wantUrl: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := IsSoundcloudUrl(tt.url); got != tt.wantUrl {
+				t.Errorf("IsSoundcloudUrl() = %v, want %v", got, tt.wantUrl)
+			}
+			if got := IsSoundcloudSetUrl(tt.url); got != tt.wantSet {
+				t.Errorf("IsSoundcloudSetUrl() = %v, want %v", got, tt.wantSet)
+			}
+		})
+	}
+}
+
+func TestBandcampUrl(t *testing.T) {
+	tests := []struct {
+		url       string
+		wantUrl   bool
+		wantAlbum bool
+	}{
+		{url: "https://artist.bandcamp.com/track/track-name", wantUrl: true},
+		{url: "https://artist.bandcamp.com/album/album-name", wantUrl: true, wantAlbum: true},
+		{url: "artist-name.bandcamp.com/album/album-name/?from=search", wantUrl: true, wantAlbum: true},
+		{url: "https://artist.bandcamp.com/", wantUrl: false},
+		{url: "https://bandcamp.com/discover", wantUrl: false},
+		{url: "https://artist.example.com/track/track-name", wantUrl: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := IsBandcampUrl(tt.url); got != tt.wantUrl {
+				t.Errorf("IsBandcampUrl() = %v, want %v", got, tt.wantUrl)
+			}
+			if got := IsBandcampAlbumUrl(tt.url); got != tt.wantAlbum {
+				t.Errorf("IsBandcampAlbumUrl() = %v, want %v", got, tt.wantAlbum)
 			}
 		})
 	}
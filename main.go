@@ -8,6 +8,7 @@ import (
 
 	"github.com/spf13/viper"
 
+	"gitlab.com/sajfer/surbot/pkg/storage"
 	"gitlab.com/sajfer/surbot/pkg/surbot"
 )
 
@@ -16,6 +17,11 @@ type envConfig struct {
 	YoutubeAPI          string `mapstructure:"YOUTUBE_API"`
 	SpotifyClientID     string `mapstructure:"SPOTIFY_CLIENTID"`
 	SpotifyClientSecret string `mapstructure:"SPOTIFY_CLIENTSECRET"`
+	StateFile           string `mapstructure:"STATE_FILE"`
+	Rejoin              bool   `mapstructure:"REJOIN"`
+	SpotifyMaxTracks    int    `mapstructure:"SPOTIFY_MAX_TRACKS"`
+	LibraryDir          string `mapstructure:"LIBRARY_DIR"`
+	InvidiousURL        string `mapstructure:"INVIDIOUS_URL"`
 }
 
 // Variables used for command line parameters
@@ -53,10 +59,48 @@ func readEnv(envConfig *envConfig) {
 	if err != nil {
 		fmt.Printf("could not bind variable, %v\n", err.Error())
 	}
+	err = viper.BindEnv("state_file")
+	if err != nil {
+		fmt.Printf("could not bind variable, %v\n", err.Error())
+	}
+	err = viper.BindEnv("rejoin")
+	if err != nil {
+		fmt.Printf("could not bind variable, %v\n", err.Error())
+	}
+	err = viper.BindEnv("spotify_max_tracks")
+	if err != nil {
+		fmt.Printf("could not bind variable, %v\n", err.Error())
+	}
+	err = viper.BindEnv("library_dir")
+	if err != nil {
+		fmt.Printf("could not bind variable, %v\n", err.Error())
+	}
+	err = viper.BindEnv("invidious_url")
+	if err != nil {
+		fmt.Printf("could not bind variable, %v\n", err.Error())
+	}
 	envConfig.Token = viper.GetString("token")
 	envConfig.YoutubeAPI = viper.GetString("youtube_api")
 	envConfig.SpotifyClientID = viper.GetString("spotify_clientid")
 	envConfig.SpotifyClientSecret = viper.GetString("spotify_clientsecret")
+	envConfig.StateFile = viper.GetString("state_file")
+	envConfig.Rejoin = viper.GetBool("rejoin")
+	envConfig.SpotifyMaxTracks = viper.GetInt("spotify_max_tracks")
+	envConfig.LibraryDir = viper.GetString("library_dir")
+	envConfig.InvidiousURL = viper.GetString("invidious_url")
+}
+
+func newStorage(path string) storage.Storage {
+	if path == "" {
+		fmt.Println("No state file configured, state will not be persisted")
+		return storage.NewMemoryStorage()
+	}
+	fileStorage, err := storage.NewFileStorage(path)
+	if err != nil {
+		fmt.Printf("could not read state file, %v\n", err.Error())
+		return storage.NewMemoryStorage()
+	}
+	return fileStorage
 }
 
 func main() {
@@ -72,6 +116,18 @@ func main() {
 	flag.StringVar(&Prefix, "p", "!", "Bot Prefix")
 	flag.Parse()
 	fmt.Printf("token: %v\n", EnvConfigs.Token)
-	bot := surbot.NewSurbot(EnvConfigs.Token, EnvConfigs.YoutubeAPI, EnvConfigs.SpotifyClientID, EnvConfigs.SpotifyClientSecret, Prefix)
+	bot := surbot.NewSurbot(EnvConfigs.Token, EnvConfigs.YoutubeAPI, EnvConfigs.SpotifyClientID, EnvConfigs.SpotifyClientSecret, Prefix, newStorage(EnvConfigs.StateFile))
+	bot.SetRejoin(EnvConfigs.Rejoin)
+	if EnvConfigs.SpotifyMaxTracks > 0 {
+		bot.SetSpotifyMaxTracks(EnvConfigs.SpotifyMaxTracks)
+	}
+	if EnvConfigs.LibraryDir != "" {
+		if err := bot.SetLibrary(EnvConfigs.LibraryDir); err != nil {
+			fmt.Printf("could not load library, %v\n", err.Error())
+		}
+	}
+	if EnvConfigs.InvidiousURL != "" {
+		bot.SetInvidious(EnvConfigs.InvidiousURL)
+	}
 	bot.StartServer()
 }
@@ -0,0 +1,205 @@
+// Package bandcamp provides bandcamp playback functionality.
+package bandcamp
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/sajfer/surbot/internal/logger"
+)
+
+const (
+	// streamTTL is how long a stream is assumed to work when its link does
+	// not say when it expires
+	streamTTL = 30 * time.Minute
+	// trackFragment prefixes the ID of a track in the fragment of its album link
+	trackFragment = "track-"
+	// requestTimeout is how long fetching a page may take
+	requestTimeout = 15 * time.Second
+)
+
+var (
+	// tralbumRegex finds the data of the album or track on its page, which
+	// the bandcamp player is built from
+	tralbumRegex = regexp.MustCompile(`data-tralbum="([^"]*)"`)
+	imageRegex   = regexp.MustCompile(`<meta property="og:image" content="([^"]*)"`)
+)
+
+// Bandcamp looks up tracks and albums on their bandcamp pages
+type Bandcamp struct {
+	client *http.Client
+}
+
+// Track is a bandcamp track
+type Track struct {
+	ID        int
+	Title     string
+	Artist    string
+	Duration  float64
+	Thumbnail string
+	// URL is the page of the track, or the album page with the ID of the
+	// track as fragment for tracks that have no page of their own
+	URL string
+	// StreamURL is a link to a 128 kbps mp3, it expires after a while
+	StreamURL    string
+	StreamExpiry time.Time
+}
+
+// Album is a bandcamp album, or a single track
+type Album struct {
+	Title  string
+	Artist string
+	Tracks []*Track
+}
+
+type tralbum struct {
+	Artist  string `json:"artist"`
+	URL     string `json:"url"`
+	Current struct {
+		Title string `json:"title"`
+		Type  string `json:"type"`
+	} `json:"current"`
+	TrackInfo []struct {
+		ID        int               `json:"id"`
+		Title     string            `json:"title"`
+		Artist    string            `json:"artist"`
+		Duration  float64           `json:"duration"`
+		TitleLink string            `json:"title_link"`
+		File      map[string]string `json:"file"`
+	} `json:"trackinfo"`
+}
+
+func NewBandcamp() *Bandcamp {
+	return &Bandcamp{client: &http.Client{Timeout: requestTimeout}}
+}
+
+// GetAlbum returns the tracks of a track or album page, tracks that cannot
+// be streamed without buying them are left out
+func (b *Bandcamp) GetAlbum(link string) (*Album, error) {
+	logger.Log.Debug("bandcamp.GetAlbum")
+	if !strings.HasPrefix(link, "http") {
+		link = "https://" + link
+	}
+	page, err := b.fetch(link)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch %s, err=%w", link, err)
+	}
+	data, err := parseTralbum(page)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s, err=%w", link, err)
+	}
+	base, err := url.Parse(data.URL)
+	if err != nil || data.URL == "" {
+		base, _ = url.Parse(link)
+	}
+	thumbnail := ""
+	if match := imageRegex.FindStringSubmatch(page); match != nil {
+		thumbnail = html.UnescapeString(match[1])
+	}
+
+	album := &Album{Artist: data.Artist}
+	if data.Current.Type == "album" {
+		album.Title = data.Current.Title
+	}
+	for _, info := range data.TrackInfo {
+		stream := info.File["mp3-128"]
+		if stream == "" {
+			continue
+		}
+		track := &Track{
+			ID:           info.ID,
+			Title:        info.Title,
+			Artist:       data.Artist,
+			Duration:     info.Duration,
+			Thumbnail:    thumbnail,
+			StreamURL:    stream,
+			StreamExpiry: streamExpiry(stream, time.Now()),
+		}
+		// Compilations list the artist of each track
+		if info.Artist != "" {
+			track.Artist = info.Artist
+		}
+		if trackURL, err := base.Parse(info.TitleLink); err == nil && info.TitleLink != "" {
+			track.URL = trackURL.String()
+		} else {
+			trackURL := *base
+			trackURL.Fragment = trackFragment + strconv.Itoa(info.ID)
+			track.URL = trackURL.String()
+		}
+		album.Tracks = append(album.Tracks, track)
+	}
+	return album, nil
+}
+
+// GetStreamURL returns a fresh stream of the track at a link of Track.URL,
+// and when it expires
+func (b *Bandcamp) GetStreamURL(link string) (string, time.Time, error) {
+	logger.Log.Debug("bandcamp.GetStreamURL")
+	album, err := b.GetAlbum(link)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	id := 0
+	if parsed, err := url.Parse(link); err == nil && strings.HasPrefix(parsed.Fragment, trackFragment) {
+		id, _ = strconv.Atoi(strings.TrimPrefix(parsed.Fragment, trackFragment))
+	}
+	for _, track := range album.Tracks {
+		// Track pages list only their own track
+		if id == 0 || track.ID == id {
+			return track.StreamURL, track.StreamExpiry, nil
+		}
+	}
+	return "", time.Time{}, fmt.Errorf("%s cannot be streamed", link)
+}
+
+// streamExpiry returns when a stream expires, the ts parameter of the link
+// is when it expires. Links without one in the future are assumed to work
+// for streamTTL.
+func streamExpiry(stream string, now time.Time) time.Time {
+	if parsed, err := url.Parse(stream); err == nil {
+		ts, err := strconv.ParseInt(parsed.Query().Get("ts"), 10, 64)
+		if err == nil && time.Unix(ts, 0).After(now) {
+			return time.Unix(ts, 0)
+		}
+	}
+	return now.Add(streamTTL)
+}
+
+// parseTralbum returns the data of the album or track on a bandcamp page
+func parseTralbum(page string) (*tralbum, error) {
+	match := tralbumRegex.FindStringSubmatch(page)
+	if match == nil {
+		return nil, fmt.Errorf("page has no bandcamp player")
+	}
+	var data tralbum
+	err := json.Unmarshal([]byte(html.UnescapeString(match[1])), &data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse player data, err=%w", err)
+	}
+	return &data, nil
+}
+
+// fetch returns the body of a page
+func (b *Bandcamp) fetch(link string) (string, error) {
+	resp, err := b.client.Get(link)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
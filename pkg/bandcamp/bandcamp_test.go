@@ -0,0 +1,152 @@
+package bandcamp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newStubServer serves the recorded pages in testdata
+func newStubServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/album/demo-tape", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/album.html")
+	})
+	mux.HandleFunc("/track/opening", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/track.html")
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><body>About</body></html>"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestBandcamp_GetAlbum(t *testing.T) {
+	server := newStubServer(t)
+	bandcamp := &Bandcamp{client: server.Client()}
+	thumbnail := "https://f4.bcbits.com/img/a1234567890_5.jpg"
+
+	tests := []struct {
+		name    string
+		path    string
+		want    *Album
+		wantErr bool
+	}{
+		{
+			name: "album",
+			path: "/album/demo-tape",
+			want: &Album{Title: "Demo Tape", Artist: "Surbot & Friends", Tracks: []*Track{
+				{
+					ID:        11,
+					Title:     "Opening",
+					Artist:    "Surbot & Friends",
+					Duration:  201.5,
+					Thumbnail: thumbnail,
+					URL:       "https://surbot.bandcamp.com/track/opening",
+					StreamURL: "https://t4.bcbits.com/stream/aaa/mp3-128/11?p=0&ts=1700000000&t=abc&token=1700000000_def",
+				},
+				{
+					ID:        12,
+					Title:     "Guest Spot",
+					Artist:    `Guest "MC"`,
+					Duration:  184.25,
+					Thumbnail: thumbnail,
+					// The track has no page of its own
+					URL:       "https://surbot.bandcamp.com/album/demo-tape#track-12",
+					StreamURL: "https://t4.bcbits.com/stream/bbb/mp3-128/12?p=0&ts=1700000000&t=ghi&token=1700000000_jkl",
+				},
+			}},
+		},
+		{
+			name: "track",
+			path: "/track/opening",
+			want: &Album{Artist: "Surbot & Friends", Tracks: []*Track{{
+				ID:        11,
+				Title:     "Opening",
+				Artist:    "Surbot & Friends",
+				Duration:  201.5,
+				Thumbnail: thumbnail,
+				URL:       "https://surbot.bandcamp.com/track/opening",
+				StreamURL: "https://t4.bcbits.com/stream/aaa/mp3-128/11?p=0&ts=1700003600&t=mno&token=1700003600_pqr",
+			}}},
+		},
+		{name: "no player", path: "/about", wantErr: true},
+		{name: "missing", path: "/album/missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bandcamp.GetAlbum(server.URL + tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetAlbum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != nil {
+				// The streams of the fixtures have expired, they are assumed to work for streamTTL
+				for _, track := range got.Tracks {
+					if !track.StreamExpiry.After(time.Now()) {
+						t.Errorf("GetAlbum() stream of %s expires at %v", track.Title, track.StreamExpiry)
+					}
+					track.StreamExpiry = time.Time{}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAlbum() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBandcamp_GetStreamURL(t *testing.T) {
+	server := newStubServer(t)
+	bandcamp := &Bandcamp{client: server.Client()}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "track page", path: "/track/opening", want: "https://t4.bcbits.com/stream/aaa/mp3-128/11?p=0&ts=1700003600&t=mno&token=1700003600_pqr"},
+		{name: "track without page", path: "/album/demo-tape#track-12", want: "https://t4.bcbits.com/stream/bbb/mp3-128/12?p=0&ts=1700000000&t=ghi&token=1700000000_jkl"},
+		{name: "track that cannot be streamed", path: "/album/demo-tape#track-13", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, expiry, err := bandcamp.GetStreamURL(server.URL + tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetStreamURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetStreamURL() = %s, want %s", got, tt.want)
+			}
+			if !tt.wantErr && !expiry.After(time.Now()) {
+				t.Errorf("GetStreamURL() expiry = %v, want a time in the future", expiry)
+			}
+		})
+	}
+}
+
+func TestStreamExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name   string
+		stream string
+		want   time.Time
+	}{
+		{name: "expires", stream: "https://t4.bcbits.com/stream/aaa/mp3-128/11?p=0&ts=1700003600&t=abc", want: time.Unix(1700003600, 0)},
+		{name: "expired", stream: "https://t4.bcbits.com/stream/aaa/mp3-128/11?p=0&ts=1699990000&t=abc", want: now.Add(streamTTL)},
+		{name: "no expiry", stream: "https://t4.bcbits.com/stream/aaa/mp3-128/11", want: now.Add(streamTTL)},
+		{name: "invalid url", stream: "://", want: now.Add(streamTTL)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := streamExpiry(tt.stream, now); !got.Equal(tt.want) {
+				t.Errorf("streamExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
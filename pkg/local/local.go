@@ -0,0 +1,234 @@
+// Package local provides playback of audio files stored on the host.
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gitlab.com/sajfer/surbot/internal/logger"
+)
+
+// extensions are the file types added to the library
+var extensions = map[string]bool{
+	".aac":  true,
+	".flac": true,
+	".m4a":  true,
+	".mp3":  true,
+	".ogg":  true,
+	".opus": true,
+	".wav":  true,
+	".webm": true,
+}
+
+// Track is an audio file in the library
+type Track struct {
+	// Name is the path of the file relative to the library, without extension
+	Name     string
+	Path     string
+	Title    string
+	Artist   string
+	Duration float64
+}
+
+// Tags are the metadata read from an audio file
+type Tags struct {
+	Title    string
+	Artist   string
+	Duration float64
+}
+
+// Library is an index of the audio files in a directory
+type Library struct {
+	dir    string
+	probe  func(path string) (*Tags, error)
+	mu     sync.RWMutex
+	tracks []*Track
+}
+
+// NewLibrary returns a library of the audio files in dir, call Scan to index them
+func NewLibrary(dir string) *Library {
+	return &Library{dir: dir, probe: Probe}
+}
+
+// Scan indexes the audio files in the library directory and its subdirectories
+func (l *Library) Scan() error {
+	logger.Log.Debug("local.Scan")
+	var tracks []*Track
+	err := filepath.WalkDir(l.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if entry.IsDir() || !extensions[ext] {
+			return nil
+		}
+		rel, err := filepath.Rel(l.dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+		track := &Track{Name: name, Path: path, Title: filepath.Base(name)}
+		tags, err := l.probe(path)
+		if err != nil {
+			logger.Log.Warningf("could not read tags of %s, err=%v", path, err)
+		} else {
+			if tags.Title != "" {
+				track.Title = tags.Title
+			}
+			track.Artist = tags.Artist
+			track.Duration = tags.Duration
+		}
+		tracks = append(tracks, track)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not scan library %s, err=%w", l.dir, err)
+	}
+	sort.Slice(tracks, func(i, j int) bool { return tracks[i].Name < tracks[j].Name })
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tracks = tracks
+	logger.Log.Infof("indexed %d tracks in %s", len(tracks), l.dir)
+	return nil
+}
+
+// Len returns the number of tracks in the library
+func (l *Library) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.tracks)
+}
+
+// Get returns the track with the given name, ignoring case
+func (l *Library) Get(name string) (*Track, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, track := range l.tracks {
+		if strings.EqualFold(track.Name, name) {
+			return track, true
+		}
+	}
+	return nil, false
+}
+
+// Search returns up to limit tracks matching the query, best matches first
+func (l *Library) Search(query string, limit int) []*Track {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	type result struct {
+		track *Track
+		score int
+	}
+	var results []result
+	l.mu.RLock()
+	for _, track := range l.tracks {
+		if score := match(track, query); score > 0 {
+			results = append(results, result{track: track, score: score})
+		}
+	}
+	l.mu.RUnlock()
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].score > results[j].score })
+	tracks := []*Track{}
+	for i := 0; i < len(results) && i < limit; i++ {
+		tracks = append(tracks, results[i].track)
+	}
+	return tracks
+}
+
+// match scores how well a track matches a lower case query, 0 if it does not
+func match(track *Track, query string) int {
+	name := strings.ToLower(track.Name)
+	text := strings.ToLower(strings.Join([]string{track.Name, track.Artist, track.Title}, " "))
+	switch {
+	case query == "":
+		return 1
+	case name == query || strings.ToLower(track.Title) == query:
+		return 4
+	case strings.Contains(text, query):
+		return 3
+	case containsWords(text, strings.Fields(query)):
+		return 2
+	case isSubsequence(name, query):
+		return 1
+	}
+	return 0
+}
+
+// containsWords returns whether text contains all the words
+func containsWords(text string, words []string) bool {
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSubsequence returns whether the characters of query appear in order in text
+func isSubsequence(text, query string) bool {
+	runes := []rune(query)
+	i := 0
+	for _, r := range text {
+		if i < len(runes) && r == runes[i] {
+			i++
+		}
+	}
+	return i == len(runes)
+}
+
+type ffprobeOutput struct {
+	Format struct {
+		Duration string            `json:"duration"`
+		Tags     map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+// Probe reads the tags of an audio file or link with ffprobe
+func Probe(path string) (*Tags, error) {
+	return ProbeContext(context.Background(), path)
+}
+
+// ProbeContext is Probe, ffprobe is killed when the context is done
+func ProbeContext(ctx context.Context, path string) (*Tags, error) {
+	out, err := exec.CommandContext(ctx, "ffprobe", "-v", "quiet", "-print_format", "json", "-show_format", path).Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not run ffprobe, err=%w", err)
+	}
+	return parseProbe(out)
+}
+
+// parseProbe parses the output of ffprobe
+func parseProbe(out []byte) (*Tags, error) {
+	var probe ffprobeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, fmt.Errorf("could not parse ffprobe output, err=%w", err)
+	}
+	tags := &Tags{}
+	// Tag names differ in case between containers, such as TITLE in flac files
+	for key, value := range probe.Format.Tags {
+		switch strings.ToLower(key) {
+		case "title":
+			tags.Title = value
+		case "artist":
+			tags.Artist = value
+		}
+	}
+	if probe.Format.Duration != "" {
+		duration, err := strconv.ParseFloat(probe.Format.Duration, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse duration %s, err=%w", probe.Format.Duration, err)
+		}
+		tags.Duration = duration
+	}
+	return tags, nil
+}
@@ -0,0 +1,131 @@
+package local
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseProbe(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    *Tags
+		wantErr bool
+	}{
+		{
+			name: "mp3",
+			out:  `{"format": {"duration": "12.500000", "tags": {"title": "Intro", "artist": "Surbot"}}}`,
+			want: &Tags{Title: "Intro", Artist: "Surbot", Duration: 12.5},
+		},
+		{
+			name: "flac",
+			out:  `{"format": {"duration": "3.000000", "tags": {"TITLE": "Outro", "ARTIST": "Surbot"}}}`,
+			want: &Tags{Title: "Outro", Artist: "Surbot", Duration: 3},
+		},
+		{name: "no tags", out: `{"format": {"duration": "1.000000"}}`, want: &Tags{Duration: 1}},
+		{name: "invalid duration", out: `{"format": {"duration": "N/A"}}`, wantErr: true},
+		{name: "invalid json", out: `ffprobe`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProbe([]byte(tt.out))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseProbe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseProbe() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestLibrary returns a scanned library of empty files, the tags of the
+// files are looked up in tags by file name
+func newTestLibrary(t *testing.T, tags map[string]*Tags, files ...string) *Library {
+	t.Helper()
+	dir := t.TempDir()
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	library := NewLibrary(dir)
+	library.probe = func(path string) (*Tags, error) {
+		if tag, ok := tags[filepath.Base(path)]; ok {
+			return tag, nil
+		}
+		return nil, errors.New("no tags")
+	}
+	if err := library.Scan(); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	return library
+}
+
+func names(tracks []*Track) []string {
+	result := []string{}
+	for _, track := range tracks {
+		result = append(result, track.Name)
+	}
+	return result
+}
+
+func TestLibrary_Scan(t *testing.T) {
+	tags := map[string]*Tags{"intro.mp3": {Title: "Welcome", Artist: "Surbot", Duration: 4}}
+	library := newTestLibrary(t, tags, "intro.mp3", "jingles/Drum Roll.OGG", "notes.txt", "jingles/cover.jpg")
+
+	if got, want := names(library.Search("", 10)), []string{"intro", "jingles/Drum Roll"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("indexed %v, want %v", got, want)
+	}
+	intro, ok := library.Get("INTRO")
+	if !ok {
+		t.Fatal("Get() did not find intro")
+	}
+	if want := (Track{Name: "intro", Path: intro.Path, Title: "Welcome", Artist: "Surbot", Duration: 4}); *intro != want {
+		t.Errorf("Get() = %+v, want %+v", *intro, want)
+	}
+	if filepath.Base(intro.Path) != "intro.mp3" {
+		t.Errorf("Get() path = %s, want a path to intro.mp3", intro.Path)
+	}
+	drum, _ := library.Get("jingles/drum roll")
+	if drum.Title != "Drum Roll" {
+		t.Errorf("untagged file has title %s, want %s", drum.Title, "Drum Roll")
+	}
+}
+
+func TestLibrary_Search(t *testing.T) {
+	tags := map[string]*Tags{
+		"intro.mp3":    {Title: "Welcome to the raid"},
+		"outro.mp3":    {Title: "Intro to the outro", Artist: "Surbot"},
+		"airhorn.opus": {Title: "Air horn"},
+	}
+	library := newTestLibrary(t, tags, "intro.mp3", "outro.mp3", "jingles/airhorn.opus")
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "intro", want: []string{"intro", "outro"}},
+		{query: "surbot outro", want: []string{"outro"}},
+		{query: "RAID", want: []string{"intro"}},
+		{query: "ahrn", want: []string{"jingles/airhorn"}},
+		{query: "missing", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := names(library.Search(tt.query, 10)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := library.Search("", 2); len(got) != 2 {
+		t.Errorf("Search() returned %d tracks, want %d", len(got), 2)
+	}
+}
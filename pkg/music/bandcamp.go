@@ -0,0 +1,59 @@
+package music
+
+import (
+	"gitlab.com/sajfer/surbot/internal/logger"
+	"gitlab.com/sajfer/surbot/internal/utils"
+	"gitlab.com/sajfer/surbot/pkg/bandcamp"
+)
+
+const sourceBandcamp = "bandcamp"
+
+// bandcampSource plays bandcamp tracks and albums
+type bandcampSource struct {
+	client *bandcamp.Bandcamp
+}
+
+// Name ...
+func (b *bandcampSource) Name() string {
+	return sourceBandcamp
+}
+
+// Match ...
+func (b *bandcampSource) Match(query string) bool {
+	return utils.IsBandcampUrl(query)
+}
+
+// Resolve ...
+func (b *bandcampSource) Resolve(query string) (*Playlist, error) {
+	logger.Log.Debug("music.bandcampSource.Resolve")
+	album, err := b.client.GetAlbum(query)
+	if err != nil {
+		return nil, err
+	}
+	// The streams expire, so they are looked up on the track pages when the
+	// tracks are about to be played
+	playlist := &Playlist{Title: album.Title, Uploader: album.Artist}
+	for _, track := range album.Tracks {
+		playlist.Songs = append(playlist.Songs, &Song{
+			Title:     track.Title,
+			Artist:    track.Artist,
+			Duration:  track.Duration,
+			Thumbnail: track.Thumbnail,
+			ID:        track.URL,
+			Source:    sourceBandcamp,
+		})
+	}
+	return playlist, nil
+}
+
+// Stream ...
+func (b *bandcampSource) Stream(song *Song) (*Song, error) {
+	stream, expiry, err := b.client.GetStreamURL(song.ID)
+	if err != nil {
+		return nil, err
+	}
+	resolved := *song
+	resolved.StreamURL = stream
+	resolved.StreamExpiry = expiry
+	return &resolved, nil
+}
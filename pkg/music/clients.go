@@ -2,125 +2,69 @@
 package music
 
 import (
-	"fmt"
-
 	"gitlab.com/sajfer/surbot/internal/logger"
-	"gitlab.com/sajfer/surbot/internal/utils"
+	"gitlab.com/sajfer/surbot/pkg/bandcamp"
+	"gitlab.com/sajfer/surbot/pkg/local"
+	"gitlab.com/sajfer/surbot/pkg/radio"
+	"gitlab.com/sajfer/surbot/pkg/soundcloud"
 	spotifyClient "gitlab.com/sajfer/surbot/pkg/spotify"
 	"gitlab.com/sajfer/surbot/pkg/youtube"
 )
 
 type MusicClients struct {
-	Youtube *youtube.Youtube
-	Spotify *spotifyClient.Client
+	Youtube    *youtube.Youtube
+	Spotify    *spotifyClient.Client
+	Soundcloud *soundcloud.Soundcloud
+	Bandcamp   *bandcamp.Bandcamp
+	Sources    *Sources
+	Library    *local.Library
+	Radio      *radio.Radio
 }
 
 func NewMusicClients(youtubeAPI, spotifyClientID, spotifyClientSecret string) *MusicClients {
 	music := &MusicClients{}
 	music.Youtube = youtube.NewYoutube(youtubeAPI)
 	music.Spotify = spotifyClient.NewSpotifyClient(spotifyClientID, spotifyClientSecret)
+	music.Soundcloud = soundcloud.NewSoundcloud()
+	music.Bandcamp = bandcamp.NewBandcamp()
+	music.Radio = radio.NewRadio()
+	youtubeSource := &youtubeSource{client: music.Youtube}
+	music.Sources = NewSources(youtubeSource,
+		&spotifySource{client: music.Spotify, youtube: youtubeSource},
+		&soundcloudSource{client: music.Soundcloud},
+		&bandcampSource{client: music.Bandcamp},
+		&httpSource{radio: music.Radio, follow: music.Radio.FollowLink, probe: probeLink},
+	)
 	return music
 }
 
-func (m *MusicClients) FetchSong(query string) (*Playlist, error) {
-	logger.Log.Debug("music.FetchSong")
-
-	if utils.IsYoutubeUrl(query) {
-		playlist, err := m.fetchYoutubeSong(query)
-		if err != nil {
-			logger.Log.Warningf("Could not fetch youtube songs, err=%v", err)
-			return nil, err
-		}
-		return playlist, nil
-	} else if utils.IsSpotifyUrl(query) {
-		playlist, err := m.fetchSpotifySong(query)
-		if err != nil {
-			logger.Log.Warningf("Could not fetch spotify songs, err=%v", err)
-			return nil, err
-		}
-		return playlist, nil
-	}
-	url := m.Youtube.SearchVideo(query).Path
-	video, err := m.Youtube.GetVideoInfo(url)
+// AddLibrary indexes the audio files in dir and makes them playable with
+// queries starting with LocalPrefix
+func (m *MusicClients) AddLibrary(dir string) error {
+	library := local.NewLibrary(dir)
+	err := library.Scan()
 	if err != nil {
-		logger.Log.Warningf("could not fetch video information for %s, err= %s", url, err)
-		return nil, err
+		return err
 	}
-	song := Song{
-		Title:     video.Songs[0].Title,
-		Duration:  video.Songs[0].Duration,
-		Thumbnail: video.Songs[0].Thumbnail,
-		ID:        video.Songs[0].ID,
-		StreamURL: video.Songs[0].StreamUrl,
-	}
-
-	playlist := Playlist{Title: "", Uploader: "", Songs: []*Song{&song}}
-	return &playlist, nil
+	m.Library = library
+	m.Sources.Register(&localSource{library: library})
+	return nil
 }
 
-func (m *MusicClients) fetchSpotifySong(query string) (*Playlist, error) {
-	logger.Log.Debug("music.fetchSpotifySong")
-	songs, err := m.Spotify.Search(query)
-	if err != nil {
-		logger.Log.Warningf("Could not search for song, err=%v", err)
-		return &Playlist{}, err
-	}
-	playlist := &Playlist{}
-	if songs.Title != "" && songs.Uploader != "" {
-		playlist.Title = songs.Title
-		playlist.Uploader = songs.Uploader
-	}
-	if len(songs.Songs) == 0 {
-		return playlist, fmt.Errorf("did not find any songs")
-	}
+// FetchSong returns the songs a link or a search query refers to
+func (m *MusicClients) FetchSong(query string) (*Playlist, error) {
+	logger.Log.Debug("music.FetchSong")
 
-	url := m.Youtube.SearchVideo(fmt.Sprintf("%s - %s", songs.Songs[0].Artist, songs.Songs[0].Name))
-	video, err := m.Youtube.GetVideoInfo(url.Path)
+	playlist, err := m.Sources.Fetch(query)
 	if err != nil {
-		logger.Log.Warningf("could not fetch video information for %s, err= %s", url, err)
-		return playlist, fmt.Errorf("failed to fetch song information")
+		logger.Log.Warningf("Could not fetch songs, err=%v", err)
+		return nil, err
 	}
-
-	playlist.Songs = append(playlist.Songs, &Song{
-		Title:     video.Songs[0].Title,
-		Duration:  video.Songs[0].Duration,
-		Thumbnail: video.Songs[0].Thumbnail,
-		ID:        video.Songs[0].ID,
-		StreamURL: video.Songs[0].StreamUrl,
-	})
 	return playlist, nil
 }
 
-func (m *MusicClients) fetchYoutubeSong(query string) (*Playlist, error) {
-	logger.Log.Debug("music.fetchYoutubeSong")
-
-	video, err := m.Youtube.GetVideoInfo(query)
-	if err != nil {
-		logger.Log.Warningf("could not fetch video information for %s, err= %s", query, err)
-		return &Playlist{}, err
-	}
-	playlist := &Playlist{}
-	if len(video.Songs) > 1 {
-
-		for _, song := range video.Songs {
-			playlist.Songs = append(playlist.Songs, &Song{
-				Title:     song.Title,
-				Duration:  song.Duration,
-				Thumbnail: song.Thumbnail,
-				ID:        song.ID,
-				StreamURL: song.StreamUrl,
-			})
-		}
-		return playlist, nil
-	}
-
-	playlist.Songs = append(playlist.Songs, &Song{
-		Title:     video.Songs[0].Title,
-		Duration:  video.Songs[0].Duration,
-		Thumbnail: video.Songs[0].Thumbnail,
-		ID:        video.Songs[0].ID,
-		StreamURL: video.Songs[0].StreamUrl,
-	})
-
-	return playlist, nil
+// Resolve returns a copy of the song with a playable stream from its source,
+// or the song itself if its stream is still valid
+func (m *MusicClients) Resolve(song *Song) (*Song, error) {
+	return m.Sources.Resolve(song)
 }
@@ -0,0 +1,108 @@
+package music
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"gitlab.com/sajfer/surbot/internal/logger"
+	"gitlab.com/sajfer/surbot/internal/utils"
+	"gitlab.com/sajfer/surbot/pkg/local"
+	"gitlab.com/sajfer/surbot/pkg/radio"
+)
+
+const (
+	sourceHTTP = "http"
+	// probeTimeout is how long reading the tags of a link may take
+	probeTimeout = 20 * time.Second
+)
+
+// httpSource plays direct audio links, internet radio streams and the
+// streams listed in M3U and PLS playlists. It matches any link, so it is
+// registered after the sources handling links of specific sites.
+type httpSource struct {
+	radio  *radio.Radio
+	follow func(link string) (string, error)
+	probe  func(link string) (*local.Tags, error)
+}
+
+// Name ...
+func (h *httpSource) Name() string {
+	return sourceHTTP
+}
+
+// Match ...
+func (h *httpSource) Match(query string) bool {
+	link, err := url.Parse(query)
+	if err != nil || link.Host == "" || (link.Scheme != "http" && link.Scheme != "https") {
+		return false
+	}
+	return !utils.IsYoutubeUrl(query) && !utils.IsSpotifyUrl(query) &&
+		!utils.IsSoundcloudUrl(query) && !utils.IsBandcampUrl(query)
+}
+
+// Resolve returns the streams of a link unresolved, their tags are read
+// when they are about to be played
+func (h *httpSource) Resolve(query string) (*Playlist, error) {
+	logger.Log.Debug("music.httpSource.Resolve")
+	streams, err := h.radio.GetStreams(query)
+	if err != nil {
+		return nil, err
+	}
+	playlist := &Playlist{}
+	for _, stream := range streams {
+		playlist.Songs = append(playlist.Songs, &Song{
+			Title:    stream.Title,
+			Duration: stream.Duration,
+			ID:       stream.URL,
+			Live:     stream.Live,
+			Source:   sourceHTTP,
+		})
+	}
+	return playlist, nil
+}
+
+// readTags fills in the tags of an audio file, the duration is needed to
+// seek in the file
+func (h *httpSource) readTags(song *Song) {
+	tags, err := h.probe(song.StreamURL)
+	if err != nil {
+		logger.Log.Warningf("could not read tags of %s, err=%v", song.StreamURL, err)
+		return
+	}
+	if tags.Title != "" {
+		song.Title = tags.Title
+	}
+	song.Artist = tags.Artist
+	if tags.Duration > 0 {
+		song.Duration = tags.Duration
+	}
+}
+
+// Stream returns the song with the link it redirects to as stream and the
+// tags of the file, the links do not expire. The link is followed again
+// since ffmpeg fetches it without the checks of the radio client.
+func (h *httpSource) Stream(song *Song) (*Song, error) {
+	if song.ID == "" {
+		return nil, fmt.Errorf("song %s has no link", song.Title)
+	}
+	link, err := h.follow(song.ID)
+	if err != nil {
+		return nil, err
+	}
+	resolved := *song
+	resolved.StreamURL = link
+	if !resolved.Live {
+		h.readTags(&resolved)
+	}
+	return &resolved, nil
+}
+
+// probeLink reads the tags of an audio link, giving up on servers that do
+// not respond
+func probeLink(link string) (*local.Tags, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	return local.ProbeContext(ctx, link)
+}
@@ -0,0 +1,97 @@
+package music
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"gitlab.com/sajfer/surbot/pkg/local"
+	"gitlab.com/sajfer/surbot/pkg/radio"
+)
+
+func TestHTTPSource_Match(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "https://radio.example.com/stream", want: true},
+		{query: "http://example.com:8000/listen.pls", want: true},
+		{query: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", want: false},
+		{query: "https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC?si=1", want: false},
+		{query: "https://soundcloud.com/artist/track-name", want: false},
+		{query: "https://artist.bandcamp.com/album/album-name", want: false},
+		{query: "ftp://example.com/song.mp3", want: false},
+		{query: "never gonna give you up", want: false},
+		{query: "file:intro", want: false},
+	}
+	source := &httpSource{}
+	for _, tt := range tests {
+		if got := source.Match(tt.query); got != tt.want {
+			t.Errorf("Match(%s) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPSource_Stream(t *testing.T) {
+	probed := 0
+	checker := radio.NewRadio()
+	source := &httpSource{
+		follow: func(link string) (string, error) {
+			if link == "http://93.184.216.34/old.mp3" {
+				return "http://93.184.216.34/song.mp3", nil
+			}
+			return link, checker.CheckLink(link)
+		},
+		probe: func(link string) (*local.Tags, error) {
+			probed++
+			return &local.Tags{Title: "Tagged", Artist: "Artist", Duration: 90}, nil
+		},
+	}
+
+	tests := []struct {
+		name       string
+		song       *Song
+		want       *Song
+		wantErr    error
+		wantProbed int
+	}{
+		{
+			name:       "file",
+			song:       &Song{ID: "http://93.184.216.34/song.mp3", Title: "song.mp3", Source: sourceHTTP},
+			want:       &Song{ID: "http://93.184.216.34/song.mp3", StreamURL: "http://93.184.216.34/song.mp3", Title: "Tagged", Artist: "Artist", Duration: 90, Source: sourceHTTP},
+			wantProbed: 1,
+		},
+		{
+			name:       "redirect",
+			song:       &Song{ID: "http://93.184.216.34/old.mp3", Title: "old.mp3", Source: sourceHTTP},
+			want:       &Song{ID: "http://93.184.216.34/old.mp3", StreamURL: "http://93.184.216.34/song.mp3", Title: "Tagged", Artist: "Artist", Duration: 90, Source: sourceHTTP},
+			wantProbed: 2,
+		},
+		{
+			name:       "live",
+			song:       &Song{ID: "http://93.184.216.34/live", Title: "Radio", Live: true, Source: sourceHTTP},
+			want:       &Song{ID: "http://93.184.216.34/live", StreamURL: "http://93.184.216.34/live", Title: "Radio", Live: true, Source: sourceHTTP},
+			wantProbed: 2,
+		},
+		{
+			name:       "private",
+			song:       &Song{ID: "http://169.254.169.254/latest/meta-data", Source: sourceHTTP},
+			wantErr:    radio.ErrPrivateAddress,
+			wantProbed: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := source.Stream(tt.song)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Stream() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Stream() = %+v, want %+v", got, tt.want)
+			}
+			if probed != tt.wantProbed {
+				t.Errorf("probed %d times, want %d", probed, tt.wantProbed)
+			}
+		})
+	}
+}
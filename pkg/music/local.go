@@ -0,0 +1,73 @@
+package music
+
+import (
+	"fmt"
+	"strings"
+
+	"gitlab.com/sajfer/surbot/internal/logger"
+	"gitlab.com/sajfer/surbot/pkg/local"
+)
+
+const (
+	sourceLocal = "local"
+	// LocalPrefix is the prefix of queries for files in the local library
+	LocalPrefix = "file:"
+)
+
+// localSource plays audio files from the local library
+type localSource struct {
+	library *local.Library
+}
+
+// Name ...
+func (l *localSource) Name() string {
+	return sourceLocal
+}
+
+// Match ...
+func (l *localSource) Match(query string) bool {
+	return strings.HasPrefix(strings.ToLower(query), LocalPrefix)
+}
+
+// Resolve returns the track with the given name, or the best match of a
+// search of the library if no track has that name
+func (l *localSource) Resolve(query string) (*Playlist, error) {
+	logger.Log.Debug("music.localSource.Resolve")
+	name := strings.TrimSpace(query[len(LocalPrefix):])
+	if name == "" {
+		return nil, fmt.Errorf("no file name given")
+	}
+	track, ok := l.library.Get(name)
+	if !ok {
+		tracks := l.library.Search(name, 1)
+		if len(tracks) == 0 {
+			return nil, fmt.Errorf("no file found for %s", name)
+		}
+		track = tracks[0]
+	}
+	return &Playlist{Songs: []*Song{newLocalSong(track)}}, nil
+}
+
+// Stream looks up the path of the file again, in case the library was rescanned
+func (l *localSource) Stream(song *Song) (*Song, error) {
+	track, ok := l.library.Get(song.ID)
+	if !ok {
+		return nil, fmt.Errorf("file %s is no longer in the library", song.ID)
+	}
+	resolved := *song
+	resolved.StreamURL = track.Path
+	return &resolved, nil
+}
+
+// newLocalSong returns the song of a track in the library, its stream is
+// the path of the file which is passed to ffmpeg as is
+func newLocalSong(track *local.Track) *Song {
+	return &Song{
+		Title:     track.Title,
+		Artist:    track.Artist,
+		Duration:  track.Duration,
+		ID:        track.Name,
+		StreamURL: track.Path,
+		Source:    sourceLocal,
+	}
+}
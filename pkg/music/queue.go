@@ -1,9 +1,50 @@
 package music
 
 import (
+	"errors"
+	"fmt"
 	"math/rand"
+	"sync"
+	"time"
 )
 
+// ErrInvalidPosition is returned when a queue position is out of range
+var ErrInvalidPosition = errors.New("music: invalid queue position")
+
+// streamMargin is how long before it expires a stream is looked up again
+const streamMargin = 10 * time.Minute
+
+// LoopMode decides what happens to a song when it has finished playing
+type LoopMode int
+
+const (
+	// LoopOff drops songs when they have finished playing
+	LoopOff LoopMode = iota
+	// LoopTrack plays the current song again
+	LoopTrack
+	// LoopQueue adds finished songs to the end of the queue
+	LoopQueue
+)
+
+var loopModes = []string{"off", "track", "queue"}
+
+func (l LoopMode) String() string {
+	if l < 0 || int(l) >= len(loopModes) {
+		return "unknown"
+	}
+	return loopModes[l]
+}
+
+// ParseLoopMode returns the loop mode with the given name
+func ParseLoopMode(name string) (LoopMode, error) {
+	for i, mode := range loopModes {
+		if mode == name {
+			return LoopMode(i), nil
+		}
+	}
+	return LoopOff, fmt.Errorf("unknown loop mode %s", name)
+}
+
 type Song struct {
 	Title     string
 	Artist    string
@@ -11,6 +52,40 @@ type Song struct {
 	Thumbnail string
 	ID        string
 	StreamURL string
+	// Source is the name of the source the song is streamed from
+	Source string
+	// RequesterID is the ID of the user that added the song to the queue
+	RequesterID     string
+	RequesterName   string
+	RequesterAvatar string
+	// ChannelID is the text channel the song was requested from
+	ChannelID string
+	// Query is searched for to find the stream of songs that have not been
+	// resolved yet, it is empty once the song is resolved
+	Query string
+	// StreamExpiry is when StreamURL stops working, zero if it does not expire
+	StreamExpiry time.Time
+	// Live songs are streams without an end, such as radio stations
+	Live bool
+}
+
+// Resolver looks up the stream of songs that were queued unresolved
+type Resolver interface {
+	// Resolve returns a resolved copy of the song, or the song itself if it
+	// is already resolved
+	Resolve(song *Song) (*Song, error)
+}
+
+// Resolved returns whether the song has a stream that can be played
+func (s *Song) Resolved() bool {
+	return s.resolvedAt(time.Now())
+}
+
+func (s *Song) resolvedAt(now time.Time) bool {
+	if s.Query != "" || s.StreamURL == "" {
+		return false
+	}
+	return s.StreamExpiry.IsZero() || now.Add(streamMargin).Before(s.StreamExpiry)
 }
 
 type Playlist struct {
@@ -19,9 +94,14 @@ type Playlist struct {
 	Songs    []*Song
 }
 
+// Music is the queue of songs of a server. It is safe for concurrent use, the
+// queue is only accessed through its methods.
 type Music struct {
-	CurrentSong *Song
-	Queue       []*Song
+	mu          sync.RWMutex
+	currentSong *Song
+	queue       []*Song
+	loop        LoopMode
+	fair        bool
 }
 
 func NewMusic() *Music {
@@ -30,10 +110,317 @@ func NewMusic() *Music {
 }
 
 func (m *Music) AddToQueue(playlist Playlist) error {
-	m.Queue = append(m.Queue, playlist.Songs...)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.fair {
+		m.queue = append(m.queue, playlist.Songs...)
+		return nil
+	}
+	for _, song := range playlist.Songs {
+		m.insertFair(song)
+	}
 	return nil
 }
 
+// insertFair inserts a song after the last song in the same or an earlier
+// round, the round of a song being the number of songs of its requester
+// ahead of it. This interleaves the songs of different requesters while
+// keeping the songs of each requester in the order they were added.
+func (m *Music) insertFair(song *Song) {
+	counts := make(map[string]int)
+	if m.currentSong != nil {
+		counts[m.currentSong.RequesterID]++
+	}
+	rounds := make([]int, len(m.queue))
+	for i, queued := range m.queue {
+		rounds[i] = counts[queued.RequesterID]
+		counts[queued.RequesterID]++
+	}
+	round := counts[song.RequesterID]
+	position := 0
+	for i := range rounds {
+		if rounds[i] <= round {
+			position = i + 1
+		}
+	}
+	m.queue = append(m.queue[:position], append([]*Song{song}, m.queue[position:]...)...)
+}
+
 func (m *Music) Shuffle() {
-	rand.Shuffle(len(m.Queue), func(i, j int) { m.Queue[i], m.Queue[j] = m.Queue[j], m.Queue[i] })
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rand.Shuffle(len(m.queue), func(i, j int) { m.queue[i], m.queue[j] = m.queue[j], m.queue[i] })
+}
+
+// CurrentSong returns the song that is playing, or nil
+func (m *Music) CurrentSong() *Song {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.currentSong
+}
+
+// SetCurrentSong sets the song that is playing
+func (m *Music) SetCurrentSong(song *Song) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.currentSong = song
+}
+
+// Loop returns the loop mode
+func (m *Music) Loop() LoopMode {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.loop
+}
+
+// SetLoop sets the loop mode
+func (m *Music) SetLoop(mode LoopMode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loop = mode
+}
+
+// Fair returns whether songs are interleaved by requester
+func (m *Music) Fair() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.fair
+}
+
+// SetFair sets whether added songs are interleaved by requester so that each
+// user gets a turn, enabling it reorders the songs already in the queue
+func (m *Music) SetFair(fair bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if fair && !m.fair {
+		queue := m.queue
+		m.queue = make([]*Song, 0, len(queue))
+		for _, song := range queue {
+			m.insertFair(song)
+		}
+	}
+	m.fair = fair
+}
+
+// Len returns the number of songs waiting in the queue
+func (m *Music) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.queue)
+}
+
+// Peek returns the next song in the queue without removing it, or nil
+func (m *Music) Peek() *Song {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.queue) == 0 {
+		return nil
+	}
+	return m.queue[0]
+}
+
+// Pop removes the next song from the queue and makes it the current song,
+// it returns nil if the queue is empty
+func (m *Music) Pop() *Song {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.queue) == 0 {
+		return nil
+	}
+	song := m.queue[0]
+	m.queue = m.queue[1:]
+	m.currentSong = song
+	return song
+}
+
+// Snapshot returns a copy of the songs waiting in the queue
+func (m *Music) Snapshot() []*Song {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*Song{}, m.queue...)
+}
+
+// ClearQueue removes all songs waiting in the queue
+func (m *Music) ClearQueue() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queue = nil
+}
+
+// Finish ends the current song, putting it back in the queue according to
+// the loop mode. Skipped songs are never played again in track mode, in fair
+// mode the song waits for its turn in queue mode.
+func (m *Music) Finish(skipped bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	song := m.currentSong
+	m.currentSong = nil
+	if song == nil {
+		return
+	}
+	switch m.loop {
+	case LoopTrack:
+		if !skipped {
+			m.queue = append([]*Song{song}, m.queue...)
+		}
+	case LoopQueue:
+		m.requeue(song)
+	}
+}
+
+// requeue adds a song that has had its turn back to the queue
+func (m *Music) requeue(songs ...*Song) {
+	if !m.fair {
+		m.queue = append(m.queue, songs...)
+		return
+	}
+	for _, song := range songs {
+		m.insertFair(song)
+	}
+}
+
+// Queue positions used by the methods below are 1-based, matching the
+// numbering shown to users, and refer to songs waiting in the queue.
+
+// validPosition reports whether position refers to a song in the queue
+func (m *Music) validPosition(position int) bool {
+	return position >= 1 && position <= len(m.queue)
+}
+
+// Remove removes the songs at positions from to to, inclusive
+func (m *Music) Remove(from, to int) ([]*Song, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.validPosition(from) || !m.validPosition(to) || from > to {
+		return nil, ErrInvalidPosition
+	}
+	removed := append([]*Song{}, m.queue[from-1:to]...)
+	m.queue = append(m.queue[:from-1], m.queue[to:]...)
+	return removed, nil
+}
+
+// Move moves the song at position from to position to
+func (m *Music) Move(from, to int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.validPosition(from) || !m.validPosition(to) {
+		return ErrInvalidPosition
+	}
+	song := m.queue[from-1]
+	m.queue = append(m.queue[:from-1], m.queue[from:]...)
+	m.queue = append(m.queue[:to-1], append([]*Song{song}, m.queue[to-1:]...)...)
+	return nil
+}
+
+// SkipTo drops the songs before position so that it is played next. In queue
+// loop mode the skipped songs are put back in the queue after it instead.
+func (m *Music) SkipTo(position int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.validPosition(position) {
+		return ErrInvalidPosition
+	}
+	skipped := m.queue[:position-1]
+	m.queue = append([]*Song{}, m.queue[position-1:]...)
+	if m.loop == LoopQueue {
+		m.requeue(skipped...)
+	}
+	return nil
+}
+
+// PlayNext adds the songs of a playlist to the front of the queue, also in
+// fair mode since it is how DJs let songs skip the turns of the requesters
+func (m *Music) PlayNext(playlist Playlist) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queue = append(append([]*Song{}, playlist.Songs...), m.queue...)
+}
+
+// Restore replaces the queue with songs saved before a restart, with the
+// song that was playing first. The saved order is kept in fair mode, it was
+// already in turns when it was saved.
+func (m *Music) Restore(current *Song, queue []*Song) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queue = append([]*Song{}, queue...)
+	if current != nil {
+		m.queue = append([]*Song{current}, m.queue...)
+	}
+}
+
+// RemoveDupes removes songs that are already playing or appear earlier in
+// the queue, and returns the number of removed songs
+func (m *Music) RemoveDupes() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := make(map[string]bool, len(m.queue)+1)
+	if m.currentSong != nil {
+		seen[m.currentSong.key()] = true
+	}
+	queue := make([]*Song, 0, len(m.queue))
+	for _, song := range m.queue {
+		if seen[song.key()] {
+			continue
+		}
+		seen[song.key()] = true
+		queue = append(queue, song)
+	}
+	removed := len(m.queue) - len(queue)
+	m.queue = queue
+	return removed
+}
+
+// RemoveRequester removes the songs added by a user from the queue and
+// returns how many were removed
+func (m *Music) RemoveRequester(requesterID string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	queue := make([]*Song, 0, len(m.queue))
+	for _, song := range m.queue {
+		if song.RequesterID != requesterID {
+			queue = append(queue, song)
+		}
+	}
+	removed := len(m.queue) - len(queue)
+	m.queue = queue
+	return removed
+}
+
+// CountRequester returns how many songs in the queue were added by a user
+func (m *Music) CountRequester(requesterID string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	count := 0
+	for _, song := range m.queue {
+		if song.RequesterID == requesterID {
+			count++
+		}
+	}
+	return count
+}
+
+// Replace replaces a song in the queue, or the current song, with another
+// version of it. It returns false if the song is no longer queued.
+func (m *Music) Replace(old, song *Song) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.currentSong == old {
+		m.currentSong = song
+		return true
+	}
+	for i, queued := range m.queue {
+		if queued == old {
+			m.queue[i] = song
+			return true
+		}
+	}
+	return false
+}
+
+// key identifies a song when looking for duplicates
+func (s *Song) key() string {
+	if s.ID != "" {
+		return s.ID
+	}
+	return s.Title
 }
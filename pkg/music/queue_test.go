@@ -0,0 +1,408 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestMusic returns a queue of songs identified by their titles
+func newTestMusic(titles ...string) *Music {
+	music := NewMusic()
+	for _, title := range titles {
+		music.queue = append(music.queue, &Song{Title: title, ID: title})
+	}
+	return music
+}
+
+func titles(songs []*Song) []string {
+	result := make([]string, 0, len(songs))
+	for _, song := range songs {
+		result = append(result, song.Title)
+	}
+	return result
+}
+
+func TestMusic_Remove(t *testing.T) {
+	tests := []struct {
+		name        string
+		from        int
+		to          int
+		wantRemoved []string
+		wantQueue   []string
+		wantErr     error
+	}{
+		{name: "single", from: 2, to: 2, wantRemoved: []string{"b"}, wantQueue: []string{"a", "c", "d"}},
+		{name: "range", from: 2, to: 3, wantRemoved: []string{"b", "c"}, wantQueue: []string{"a", "d"}},
+		{name: "all", from: 1, to: 4, wantRemoved: []string{"a", "b", "c", "d"}, wantQueue: []string{}},
+		{name: "zero", from: 0, to: 1, wantQueue: []string{"a", "b", "c", "d"}, wantErr: ErrInvalidPosition},
+		{name: "past end", from: 4, to: 5, wantQueue: []string{"a", "b", "c", "d"}, wantErr: ErrInvalidPosition},
+		{name: "reversed", from: 3, to: 2, wantQueue: []string{"a", "b", "c", "d"}, wantErr: ErrInvalidPosition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMusic("a", "b", "c", "d")
+			removed, err := m.Remove(tt.from, tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Remove() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(titles(removed), tt.wantRemoved) {
+				t.Errorf("Remove() = %v, want %v", titles(removed), tt.wantRemoved)
+			}
+			if got := titles(m.Snapshot()); !reflect.DeepEqual(got, tt.wantQueue) {
+				t.Errorf("Queue = %v, want %v", got, tt.wantQueue)
+			}
+		})
+	}
+}
+
+func TestMusic_Move(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      int
+		to        int
+		wantQueue []string
+		wantErr   error
+	}{
+		{name: "forward", from: 1, to: 3, wantQueue: []string{"b", "c", "a", "d"}},
+		{name: "backward", from: 4, to: 1, wantQueue: []string{"d", "a", "b", "c"}},
+		{name: "same", from: 2, to: 2, wantQueue: []string{"a", "b", "c", "d"}},
+		{name: "to end", from: 2, to: 4, wantQueue: []string{"a", "c", "d", "b"}},
+		{name: "invalid from", from: 5, to: 1, wantQueue: []string{"a", "b", "c", "d"}, wantErr: ErrInvalidPosition},
+		{name: "invalid to", from: 1, to: 0, wantQueue: []string{"a", "b", "c", "d"}, wantErr: ErrInvalidPosition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMusic("a", "b", "c", "d")
+			if err := m.Move(tt.from, tt.to); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Move() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := titles(m.Snapshot()); !reflect.DeepEqual(got, tt.wantQueue) {
+				t.Errorf("Queue = %v, want %v", got, tt.wantQueue)
+			}
+		})
+	}
+}
+
+func TestMusic_SkipTo(t *testing.T) {
+	tests := []struct {
+		name      string
+		position  int
+		loop      LoopMode
+		wantQueue []string
+		wantErr   error
+	}{
+		{name: "first", position: 1, wantQueue: []string{"a", "b", "c", "d"}},
+		{name: "middle", position: 3, wantQueue: []string{"c", "d"}},
+		{name: "loop queue", position: 3, loop: LoopQueue, wantQueue: []string{"c", "d", "a", "b"}},
+		{name: "invalid", position: 5, wantQueue: []string{"a", "b", "c", "d"}, wantErr: ErrInvalidPosition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMusic("a", "b", "c", "d")
+			m.SetLoop(tt.loop)
+			if err := m.SkipTo(tt.position); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SkipTo() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := titles(m.Snapshot()); !reflect.DeepEqual(got, tt.wantQueue) {
+				t.Errorf("Queue = %v, want %v", got, tt.wantQueue)
+			}
+		})
+	}
+}
+
+func TestMusic_PlayNext(t *testing.T) {
+	m := newTestMusic("a", "b")
+	m.PlayNext(Playlist{Songs: []*Song{{Title: "x"}, {Title: "y"}}})
+	want := []string{"x", "y", "a", "b"}
+	if got := titles(m.Snapshot()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Queue = %v, want %v", got, want)
+	}
+}
+
+func TestMusic_RemoveDupes(t *testing.T) {
+	m := newTestMusic("a", "b", "a", "c", "b", "d")
+	m.SetCurrentSong(&Song{Title: "d", ID: "d"})
+	if got := m.RemoveDupes(); got != 3 {
+		t.Errorf("RemoveDupes() = %v, want %v", got, 3)
+	}
+	want := []string{"a", "b", "c"}
+	if got := titles(m.Snapshot()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Queue = %v, want %v", got, want)
+	}
+}
+
+func TestMusic_RemoveRequester(t *testing.T) {
+	m := newTestMusic("a", "b", "c", "d")
+	for i, song := range m.queue {
+		song.RequesterID = fmt.Sprint(i % 2)
+	}
+	if got := m.CountRequester("1"); got != 2 {
+		t.Errorf("CountRequester() = %v, want %v", got, 2)
+	}
+	if got := m.RemoveRequester("1"); got != 2 {
+		t.Errorf("RemoveRequester() = %v, want %v", got, 2)
+	}
+	want := []string{"a", "c"}
+	if got := titles(m.Snapshot()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Queue = %v, want %v", got, want)
+	}
+	if got := m.CountRequester("1"); got != 0 {
+		t.Errorf("CountRequester() after removal = %v, want %v", got, 0)
+	}
+}
+
+// requested returns a playlist of songs added by a requester
+func requested(requester string, titles ...string) Playlist {
+	var playlist Playlist
+	for _, title := range titles {
+		playlist.Songs = append(playlist.Songs, &Song{Title: title, ID: title, RequesterID: requester})
+	}
+	return playlist
+}
+
+func TestMusic_Fair(t *testing.T) {
+	tests := []struct {
+		name    string
+		current *Song
+		added   []Playlist
+		want    []string
+	}{
+		{
+			name:  "interleaves requesters",
+			added: []Playlist{requested("a", "a1", "a2", "a3"), requested("b", "b1", "b2"), requested("c", "c1")},
+			want:  []string{"a1", "b1", "c1", "a2", "b2", "a3"},
+		},
+		{
+			name:  "keeps order of each requester",
+			added: []Playlist{requested("a", "a1", "a2"), requested("b", "b1"), requested("a", "a3"), requested("b", "b2", "b3")},
+			want:  []string{"a1", "b1", "a2", "b2", "a3", "b3"},
+		},
+		{
+			name:    "counts the current song",
+			current: &Song{Title: "current", RequesterID: "a"},
+			added:   []Playlist{requested("a", "a1", "a2"), requested("b", "b1")},
+			want:    []string{"b1", "a1", "a2"},
+		},
+		{
+			name:  "single requester",
+			added: []Playlist{requested("a", "a1", "a2"), requested("a", "a3")},
+			want:  []string{"a1", "a2", "a3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMusic()
+			m.SetFair(true)
+			m.SetCurrentSong(tt.current)
+			for _, playlist := range tt.added {
+				if err := m.AddToQueue(playlist); err != nil {
+					t.Fatalf("AddToQueue() error = %v", err)
+				}
+			}
+			if got := titles(m.Snapshot()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Queue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMusic_FairBypass covers the ways songs are added in fair mode besides
+// AddToQueue, only finished songs in queue loop mode wait for their turn
+func TestMusic_FairBypass(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(m *Music)
+		want []string
+	}{
+		{
+			name: "finished song waits for its turn",
+			run: func(m *Music) {
+				m.SetLoop(LoopQueue)
+				m.Finish(false)
+			},
+			want: []string{"b1", "a1", "b2", "b3"},
+		},
+		{
+			name: "play next skips the turns",
+			run:  func(m *Music) { m.PlayNext(requested("c", "c1")) },
+			want: []string{"c1", "b1", "b2", "b3"},
+		},
+		{
+			name: "restore keeps the saved order",
+			run:  func(m *Music) { m.Restore(&Song{Title: "a1", RequesterID: "a"}, requested("b", "b3", "b1").Songs) },
+			want: []string{"a1", "b3", "b1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMusic()
+			m.SetFair(true)
+			if err := m.AddToQueue(requested("b", "b1", "b2", "b3")); err != nil {
+				t.Fatalf("AddToQueue() error = %v", err)
+			}
+			m.SetCurrentSong(&Song{Title: "a1", RequesterID: "a"})
+			tt.run(m)
+			if got := titles(m.Snapshot()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Queue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMusic_SetFair(t *testing.T) {
+	m := NewMusic()
+	for _, playlist := range []Playlist{requested("a", "a1", "a2", "a3"), requested("b", "b1", "b2")} {
+		if err := m.AddToQueue(playlist); err != nil {
+			t.Fatalf("AddToQueue() error = %v", err)
+		}
+	}
+	m.SetFair(true)
+	want := []string{"a1", "b1", "a2", "b2", "a3"}
+	if got := titles(m.Snapshot()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Queue after SetFair(true) = %v, want %v", got, want)
+	}
+
+	m.SetFair(false)
+	if err := m.AddToQueue(requested("c", "c1")); err != nil {
+		t.Fatalf("AddToQueue() error = %v", err)
+	}
+	want = append(want, "c1")
+	if got := titles(m.Snapshot()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Queue after SetFair(false) = %v, want %v", got, want)
+	}
+}
+
+func TestMusic_Replace(t *testing.T) {
+	m := newTestMusic("a", "b")
+	current := &Song{Title: "current", Query: "artist - current"}
+	m.SetCurrentSong(current)
+	queued := m.Peek()
+
+	resolved := &Song{Title: "current", ID: "current", StreamURL: "stream"}
+	if !m.Replace(current, resolved) {
+		t.Errorf("Replace() of current song = false, want true")
+	}
+	if got := m.CurrentSong(); got != resolved || !got.Resolved() {
+		t.Errorf("CurrentSong() = %v, want %v", got, resolved)
+	}
+
+	replacement := &Song{Title: "a2", ID: "a2"}
+	if !m.Replace(queued, replacement) {
+		t.Errorf("Replace() of queued song = false, want true")
+	}
+	want := []string{"a2", "b"}
+	if got := titles(m.Snapshot()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Queue = %v, want %v", got, want)
+	}
+
+	if m.Replace(queued, replacement) {
+		t.Errorf("Replace() of removed song = true, want false")
+	}
+}
+
+func TestSong_Resolved(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		song Song
+		want bool
+	}{
+		{name: "query", song: Song{Query: "artist - title"}, want: false},
+		{name: "no stream", song: Song{ID: "a"}, want: false},
+		{name: "stream without expiry", song: Song{ID: "a", StreamURL: "stream"}, want: true},
+		{name: "valid stream", song: Song{ID: "a", StreamURL: "stream", StreamExpiry: now.Add(time.Hour)}, want: true},
+		{name: "expiring stream", song: Song{ID: "a", StreamURL: "stream", StreamExpiry: now.Add(streamMargin / 2)}, want: false},
+		{name: "expired stream", song: Song{ID: "a", StreamURL: "stream", StreamExpiry: now.Add(-time.Hour)}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.song.resolvedAt(now); got != tt.want {
+				t.Errorf("Resolved() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMusic_Finish(t *testing.T) {
+	tests := []struct {
+		name      string
+		loop      LoopMode
+		skipped   bool
+		wantQueue []string
+	}{
+		{name: "off", loop: LoopOff, wantQueue: []string{"a"}},
+		{name: "track", loop: LoopTrack, wantQueue: []string{"current", "a"}},
+		{name: "track skipped", loop: LoopTrack, skipped: true, wantQueue: []string{"a"}},
+		{name: "queue", loop: LoopQueue, wantQueue: []string{"a", "current"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMusic("a")
+			m.SetLoop(tt.loop)
+			m.SetCurrentSong(&Song{Title: "current"})
+			m.Finish(tt.skipped)
+			if got := m.CurrentSong(); got != nil {
+				t.Errorf("CurrentSong() = %v, want nil", got)
+			}
+			if got := titles(m.Snapshot()); !reflect.DeepEqual(got, tt.wantQueue) {
+				t.Errorf("Queue = %v, want %v", got, tt.wantQueue)
+			}
+		})
+	}
+}
+
+// TestMusic_Concurrent exercises the queue from many goroutines, run it with
+// -race to detect unsynchronized access
+func TestMusic_Concurrent(t *testing.T) {
+	const workers = 8
+	const iterations = 200
+
+	m := newTestMusic("a", "b", "c")
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				song := &Song{Title: fmt.Sprintf("%d-%d", w, i), ID: fmt.Sprintf("%d-%d", w, i)}
+				switch i % 10 {
+				case 0:
+					_ = m.AddToQueue(Playlist{Songs: []*Song{song}})
+				case 1:
+					m.PlayNext(Playlist{Songs: []*Song{song}})
+				case 2:
+					m.Pop()
+				case 3:
+					m.Shuffle()
+				case 4:
+					_ = m.Move(1, m.Len())
+				case 5:
+					_, _ = m.Remove(1, 1)
+				case 6:
+					m.Finish(i%2 == 0)
+				case 7:
+					m.SetLoop(LoopMode(i % 3))
+				case 8:
+					m.RemoveDupes()
+				case 9:
+					for _, queued := range m.Snapshot() {
+						_ = queued.Title
+					}
+					_ = m.Peek()
+					_ = m.CurrentSong()
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for _, song := range m.Snapshot() {
+		if song == nil {
+			t.Fatal("queue contains a nil song")
+		}
+	}
+}
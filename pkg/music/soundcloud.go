@@ -0,0 +1,65 @@
+package music
+
+import (
+	"fmt"
+	"strconv"
+
+	"gitlab.com/sajfer/surbot/internal/logger"
+	"gitlab.com/sajfer/surbot/internal/utils"
+	"gitlab.com/sajfer/surbot/pkg/soundcloud"
+)
+
+const sourceSoundcloud = "soundcloud"
+
+// soundcloudSource plays soundcloud tracks and sets
+type soundcloudSource struct {
+	client *soundcloud.Soundcloud
+}
+
+// Name ...
+func (s *soundcloudSource) Name() string {
+	return sourceSoundcloud
+}
+
+// Match ...
+func (s *soundcloudSource) Match(query string) bool {
+	return utils.IsSoundcloudUrl(query)
+}
+
+// Resolve ...
+func (s *soundcloudSource) Resolve(query string) (*Playlist, error) {
+	logger.Log.Debug("music.soundcloudSource.Resolve")
+	tracks, err := s.client.GetTracks(query)
+	if err != nil {
+		return nil, err
+	}
+	// The streams expire, so they are looked up when the tracks are about to be played
+	playlist := &Playlist{Title: tracks.Title, Uploader: tracks.Uploader}
+	for _, track := range tracks.Tracks {
+		playlist.Songs = append(playlist.Songs, &Song{
+			Title:     track.Title,
+			Artist:    track.Artist,
+			Duration:  track.Duration,
+			Thumbnail: track.Thumbnail,
+			ID:        strconv.Itoa(track.ID),
+			Source:    sourceSoundcloud,
+		})
+	}
+	return playlist, nil
+}
+
+// Stream ...
+func (s *soundcloudSource) Stream(song *Song) (*Song, error) {
+	id, err := strconv.Atoi(song.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid soundcloud track %s", song.ID)
+	}
+	stream, expiry, err := s.client.GetStreamURL(id)
+	if err != nil {
+		return nil, err
+	}
+	resolved := *song
+	resolved.StreamURL = stream
+	resolved.StreamExpiry = expiry
+	return &resolved, nil
+}
@@ -0,0 +1,90 @@
+package music
+
+import (
+	"fmt"
+)
+
+// Source is a provider of songs, such as youtube or spotify
+type Source interface {
+	// Name identifies the source, it is stored in the songs it returns
+	Name() string
+	// Match returns whether the source handles the query
+	Match(query string) bool
+	// Resolve returns the songs a query refers to
+	Resolve(query string) (*Playlist, error)
+	// Stream returns a copy of a song from this source with a playable
+	// StreamURL, which is either a URL or a path that ffmpeg can read
+	Stream(song *Song) (*Song, error)
+}
+
+// Sources is a registry of sources. A query is handled by the first source
+// that matches it, or by the fallback source if none does.
+type Sources struct {
+	sources  []Source
+	fallback Source
+}
+
+// NewSources returns a registry using fallback for queries no source matches
+func NewSources(fallback Source, sources ...Source) *Sources {
+	return &Sources{sources: sources, fallback: fallback}
+}
+
+// Register adds a source, it is matched after the sources registered before it
+func (s *Sources) Register(source Source) {
+	s.sources = append(s.sources, source)
+}
+
+// Lookup returns the source with the given name, songs without a source
+// belong to the fallback source
+func (s *Sources) Lookup(name string) (Source, bool) {
+	if name == "" || name == s.fallback.Name() {
+		return s.fallback, true
+	}
+	for _, source := range s.sources {
+		if source.Name() == name {
+			return source, true
+		}
+	}
+	return nil, false
+}
+
+// Match returns the source handling a query
+func (s *Sources) Match(query string) Source {
+	for _, source := range s.sources {
+		if source.Match(query) {
+			return source
+		}
+	}
+	return s.fallback
+}
+
+// Fetch returns the songs a query refers to
+func (s *Sources) Fetch(query string) (*Playlist, error) {
+	source := s.Match(query)
+	playlist, err := source.Resolve(query)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch songs from %s, err=%w", source.Name(), err)
+	}
+	if len(playlist.Songs) == 0 {
+		return nil, fmt.Errorf("did not find any songs on %s", source.Name())
+	}
+	for _, song := range playlist.Songs {
+		if song.Source == "" {
+			song.Source = source.Name()
+		}
+	}
+	return playlist, nil
+}
+
+// Resolve returns a copy of the song with a playable stream from its source,
+// or the song itself if its stream is still valid
+func (s *Sources) Resolve(song *Song) (*Song, error) {
+	if song.Resolved() {
+		return song, nil
+	}
+	source, ok := s.Lookup(song.Source)
+	if !ok {
+		return nil, fmt.Errorf("unknown source %s", song.Source)
+	}
+	return source.Stream(song)
+}
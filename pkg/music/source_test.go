@@ -0,0 +1,118 @@
+package music
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeSource handles queries starting with its name followed by a colon,
+// the rest of the query is a comma separated list of song titles
+type fakeSource struct {
+	name     string
+	streamed []string
+}
+
+func (f *fakeSource) Name() string {
+	return f.name
+}
+
+func (f *fakeSource) Match(query string) bool {
+	return strings.HasPrefix(query, f.name+":")
+}
+
+func (f *fakeSource) Resolve(query string) (*Playlist, error) {
+	query = strings.TrimPrefix(query, f.name+":")
+	if query == "error" {
+		return nil, errors.New("fake error")
+	}
+	playlist := &Playlist{Title: f.name}
+	for _, title := range strings.Split(query, ",") {
+		if title != "" {
+			playlist.Songs = append(playlist.Songs, &Song{Title: title, ID: title})
+		}
+	}
+	return playlist, nil
+}
+
+func (f *fakeSource) Stream(song *Song) (*Song, error) {
+	f.streamed = append(f.streamed, song.Title)
+	resolved := *song
+	resolved.StreamURL = f.name + "://" + song.ID
+	return &resolved, nil
+}
+
+func TestSources_Fetch(t *testing.T) {
+	fallback := &fakeSource{name: "search"}
+	sources := NewSources(fallback, &fakeSource{name: "one"})
+	sources.Register(&fakeSource{name: "two"})
+
+	tests := []struct {
+		query      string
+		wantSource string
+		wantTitles []string
+		wantErr    bool
+	}{
+		{query: "one:a,b", wantSource: "one", wantTitles: []string{"a", "b"}},
+		{query: "two:c", wantSource: "two", wantTitles: []string{"c"}},
+		{query: "free text", wantSource: "search", wantTitles: []string{"free text"}},
+		{query: "one:", wantErr: true},
+		{query: "two:error", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			playlist, err := sources.Fetch(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := titles(playlist.Songs); !reflect.DeepEqual(got, tt.wantTitles) {
+				t.Errorf("Fetch() songs = %v, want %v", got, tt.wantTitles)
+			}
+			for _, song := range playlist.Songs {
+				if song.Source != tt.wantSource {
+					t.Errorf("song %s has source %s, want %s", song.Title, song.Source, tt.wantSource)
+				}
+			}
+		})
+	}
+}
+
+func TestSources_Resolve(t *testing.T) {
+	fallback := &fakeSource{name: "search"}
+	one := &fakeSource{name: "one"}
+	sources := NewSources(fallback, one)
+
+	tests := []struct {
+		name       string
+		song       *Song
+		wantStream string
+		wantErr    bool
+	}{
+		{name: "registered source", song: &Song{Title: "a", ID: "a", Source: "one"}, wantStream: "one://a"},
+		{name: "fallback source", song: &Song{Title: "b", ID: "b", Source: "search"}, wantStream: "search://b"},
+		{name: "no source", song: &Song{Title: "c", ID: "c"}, wantStream: "search://c"},
+		{name: "resolved", song: &Song{Title: "d", ID: "d", Source: "one", StreamURL: "cached"}, wantStream: "cached"},
+		{name: "unknown source", song: &Song{Title: "e", ID: "e", Source: "unknown"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := sources.Resolve(tt.song)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if resolved.StreamURL != tt.wantStream {
+				t.Errorf("Resolve() stream = %s, want %s", resolved.StreamURL, tt.wantStream)
+			}
+		})
+	}
+	if want := []string{"a"}; !reflect.DeepEqual(one.streamed, want) {
+		t.Errorf("source one streamed %v, want %v", one.streamed, want)
+	}
+}
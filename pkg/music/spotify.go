@@ -0,0 +1,60 @@
+package music
+
+import (
+	"fmt"
+
+	"gitlab.com/sajfer/surbot/internal/logger"
+	"gitlab.com/sajfer/surbot/internal/utils"
+	spotifyClient "gitlab.com/sajfer/surbot/pkg/spotify"
+)
+
+const sourceSpotify = "spotify"
+
+// spotifySource queues the tracks of spotify links, the tracks are streamed
+// from youtube
+type spotifySource struct {
+	client  *spotifyClient.Client
+	youtube Source
+}
+
+// Name ...
+func (s *spotifySource) Name() string {
+	return sourceSpotify
+}
+
+// Match ...
+func (s *spotifySource) Match(query string) bool {
+	return utils.IsSpotifyUrl(query)
+}
+
+// Resolve ...
+func (s *spotifySource) Resolve(query string) (*Playlist, error) {
+	logger.Log.Debug("music.spotifySource.Resolve")
+	songs, err := s.client.Search(query)
+	if err != nil {
+		return nil, fmt.Errorf("could not search for song, err=%w", err)
+	}
+	playlist := &Playlist{}
+	if songs.Title != "" && songs.Uploader != "" {
+		playlist.Title = songs.Title
+		playlist.Uploader = songs.Uploader
+	}
+
+	// The tracks are looked up on youtube when they are about to be played,
+	// so that long playlists are queued at once without using search quota
+	for _, song := range songs.Songs {
+		playlist.Songs = append(playlist.Songs, &Song{
+			Title:    song.Name,
+			Artist:   song.Artist,
+			Duration: song.Duration,
+			Query:    fmt.Sprintf("%s - %s", song.Artist, song.Name),
+			Source:   sourceSpotify,
+		})
+	}
+	return playlist, nil
+}
+
+// Stream ...
+func (s *spotifySource) Stream(song *Song) (*Song, error) {
+	return s.youtube.Stream(song)
+}
@@ -0,0 +1,88 @@
+package music
+
+import (
+	"fmt"
+
+	"gitlab.com/sajfer/surbot/internal/logger"
+	"gitlab.com/sajfer/surbot/internal/utils"
+	"gitlab.com/sajfer/surbot/pkg/youtube"
+)
+
+const sourceYoutube = "youtube"
+
+// youtubeSource plays youtube videos and playlists, and searches youtube for
+// queries that no other source handles
+type youtubeSource struct {
+	client *youtube.Youtube
+}
+
+// Name ...
+func (y *youtubeSource) Name() string {
+	return sourceYoutube
+}
+
+// Match ...
+func (y *youtubeSource) Match(query string) bool {
+	return utils.IsYoutubeUrl(query)
+}
+
+// Resolve ...
+func (y *youtubeSource) Resolve(query string) (*Playlist, error) {
+	logger.Log.Debug("music.youtubeSource.Resolve")
+
+	url := query
+	if !utils.IsYoutubeUrl(query) {
+		result := y.client.SearchVideo(query)
+		if result == nil {
+			return nil, fmt.Errorf("no video found for %s", query)
+		}
+		url = result.Path
+	}
+	video, err := y.client.GetVideoInfo(url)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch video information for %s, err=%w", url, err)
+	}
+	playlist := &Playlist{Title: video.Title, Uploader: video.Uploader}
+	for _, song := range video.Songs {
+		playlist.Songs = append(playlist.Songs, newSong(song))
+	}
+	return playlist, nil
+}
+
+// Stream looks up the stream of a video, searching for it first if the song
+// was queued by a search query
+func (y *youtubeSource) Stream(song *Song) (*Song, error) {
+	resolved := *song
+	if resolved.Query != "" {
+		result := y.client.SearchVideo(resolved.Query)
+		if result == nil {
+			return nil, fmt.Errorf("no video found for %s", resolved.Query)
+		}
+		resolved.ID = result.VideoID
+		resolved.Query = ""
+	}
+	video, err := y.client.GetVideoInfo(resolved.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch video information for %s, err=%w", resolved.ID, err)
+	}
+	resolved.Thumbnail = video.Songs[0].Thumbnail
+	resolved.StreamURL = video.Songs[0].StreamUrl
+	resolved.StreamExpiry = video.Songs[0].StreamExpiry
+	if resolved.Duration == 0 {
+		resolved.Duration = video.Songs[0].Duration
+	}
+	return &resolved, nil
+}
+
+// newSong returns the song of a youtube video
+func newSong(video *youtube.Video) *Song {
+	return &Song{
+		Title:        video.Title,
+		Duration:     video.Duration,
+		Thumbnail:    video.Thumbnail,
+		ID:           video.ID,
+		StreamURL:    video.StreamUrl,
+		StreamExpiry: video.StreamExpiry,
+		Source:       sourceYoutube,
+	}
+}
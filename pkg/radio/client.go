@@ -0,0 +1,133 @@
+package radio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const (
+	// dialTimeout is how long connecting to a server may take
+	dialTimeout = 10 * time.Second
+	// headerTimeout is how long a server may take to respond, the body of a
+	// live stream is read for as long as it plays
+	headerTimeout = 10 * time.Second
+	// lookupTimeout is how long looking up the addresses of a host may take
+	lookupTimeout = 5 * time.Second
+)
+
+// ErrPrivateAddress is returned for links to hosts that are not on the
+// internet, such as services in the cluster of the bot
+var ErrPrivateAddress = errors.New("radio: link points to a private address")
+
+// reservedPrefixes are the ranges, besides private, loopback, link local and
+// multicast addresses, that are not reachable on the internet
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// newClient returns a client that only connects to public addresses. The
+// address is checked when connecting, after the host has been looked up, so
+// redirects and hosts resolving to a private address are refused as well.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: dialTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !publicAddress(addr) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{Transport: &http.Transport{
+		// The address of a proxy would be checked instead of the server
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   dialTimeout,
+		ResponseHeaderTimeout: headerTimeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}}
+}
+
+// publicAddress returns whether an address is reachable on the internet
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// FollowLink returns the link that a link redirects to, refusing private
+// addresses on the way. ffmpeg follows redirects without any checks, so it
+// is given the final link.
+func (r *Radio) FollowLink(link string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", fmt.Errorf("could not create request, err=%w", err)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch %s, err=%w", link, err)
+	}
+	// Only the headers are needed, live streams do not end
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not fetch %s, status=%s", link, resp.Status)
+	}
+	final := resp.Request.URL.String()
+	err = r.CheckLink(final)
+	if err != nil {
+		return "", err
+	}
+	return final, nil
+}
+
+// CheckLink returns ErrPrivateAddress if the host of a link has an address
+// that is not public. Links are checked before they are handed to ffmpeg,
+// which fetches them without the checks of the client.
+func (r *Radio) CheckLink(link string) error {
+	if r.allowPrivate {
+		return nil
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return fmt.Errorf("could not parse %s, err=%w", link, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", parsed.Hostname())
+	if err != nil {
+		return fmt.Errorf("could not look up %s, err=%w", parsed.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !publicAddress(addr) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package radio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Entry is a stream listed in a playlist file
+type Entry struct {
+	URL   string
+	Title string
+	// Duration is the length of the stream in seconds, 0 if the playlist does
+	// not say and negative for live streams
+	Duration float64
+}
+
+// ParseM3U parses an M3U playlist, the titles and durations are read from
+// #EXTINF lines of extended M3U playlists
+func ParseM3U(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	var next Entry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		switch {
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info := strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)
+			// The duration can be followed by attributes, such as tvg-id="..."
+			fields := strings.Fields(info[0])
+			if len(fields) > 0 {
+				next.Duration, _ = strconv.ParseFloat(fields[0], 64)
+			}
+			if len(info) == 2 {
+				next.Title = strings.TrimSpace(info[1])
+			}
+		case line == "" || strings.HasPrefix(line, "#"):
+		default:
+			next.URL = line
+			entries = append(entries, next)
+			next = Entry{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read m3u playlist, err=%w", err)
+	}
+	return entries, nil
+}
+
+// ParsePLS parses a PLS playlist, entries are ordered by their number
+func ParsePLS(r io.Reader) ([]Entry, error) {
+	entries := map[int]*Entry{}
+	entry := func(n int) *Entry {
+		if entries[n] == nil {
+			entries[n] = &Entry{}
+		}
+		return entries[n]
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		for _, field := range []string{"file", "title", "length"} {
+			if !strings.HasPrefix(key, field) {
+				continue
+			}
+			n, err := strconv.Atoi(key[len(field):])
+			if err != nil {
+				continue
+			}
+			switch field {
+			case "file":
+				entry(n).URL = value
+			case "title":
+				entry(n).Title = value
+			case "length":
+				entry(n).Duration, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read pls playlist, err=%w", err)
+	}
+
+	numbers := make([]int, 0, len(entries))
+	for n, entry := range entries {
+		if entry.URL != "" {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+	result := make([]Entry, 0, len(numbers))
+	for _, n := range numbers {
+		result = append(result, *entries[n])
+	}
+	return result, nil
+}
@@ -0,0 +1,91 @@
+package radio
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseM3U(t *testing.T) {
+	tests := []struct {
+		name     string
+		playlist string
+		want     []Entry
+	}{
+		{
+			name:     "plain",
+			playlist: "http://radio.example.com/stream\n\nhttp://radio.example.com/backup\n",
+			want:     []Entry{{URL: "http://radio.example.com/stream"}, {URL: "http://radio.example.com/backup"}},
+		},
+		{
+			name: "extended",
+			playlist: "\ufeff#EXTM3U\r\n" +
+				"#EXTINF:-1 tvg-id=\"radio\",Radio Surbot\r\n" +
+				"http://radio.example.com/stream\r\n" +
+				"#EXTINF:185,Artist - Song, with a comma\r\n" +
+				"songs/song.mp3\r\n",
+			want: []Entry{
+				{URL: "http://radio.example.com/stream", Title: "Radio Surbot", Duration: -1},
+				{URL: "songs/song.mp3", Title: "Artist - Song, with a comma", Duration: 185},
+			},
+		},
+		{name: "empty", playlist: "#EXTM3U\n", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseM3U(strings.NewReader(tt.playlist))
+			if err != nil {
+				t.Fatalf("ParseM3U() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseM3U() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePLS(t *testing.T) {
+	tests := []struct {
+		name     string
+		playlist string
+		want     []Entry
+	}{
+		{
+			name: "shoutcast",
+			playlist: "[playlist]\n" +
+				"NumberOfEntries=2\n" +
+				"File2=http://radio.example.com:8002/\n" +
+				"Title2=Radio Surbot (backup)\n" +
+				"Length2=-1\n" +
+				"File1=http://radio.example.com:8000/\n" +
+				"Title1=Radio Surbot\n" +
+				"Length1=-1\n" +
+				"Version=2\n",
+			want: []Entry{
+				{URL: "http://radio.example.com:8000/", Title: "Radio Surbot", Duration: -1},
+				{URL: "http://radio.example.com:8002/", Title: "Radio Surbot (backup)", Duration: -1},
+			},
+		},
+		{
+			name:     "lower case keys",
+			playlist: "[playlist]\r\nfile1 = http://example.com/song.mp3\r\nlength1 = 60\r\n",
+			want:     []Entry{{URL: "http://example.com/song.mp3", Duration: 60}},
+		},
+		{
+			name:     "title without file",
+			playlist: "[playlist]\nTitle1=Nothing\n",
+			want:     []Entry{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePLS(strings.NewReader(tt.playlist))
+			if err != nil {
+				t.Fatalf("ParsePLS() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePLS() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
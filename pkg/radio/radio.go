@@ -0,0 +1,252 @@
+// Package radio provides playback of direct audio links and internet radio streams.
+package radio
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/sajfer/surbot/internal/logger"
+)
+
+const (
+	// maxPlaylistSize is the largest playlist file that is read
+	maxPlaylistSize = 1 << 20
+	// maxPlaylistEntries is the number of streams taken from a playlist
+	maxPlaylistEntries = 100
+	// requestTimeout is how long fetching a link and reading a playlist may take
+	requestTimeout = 30 * time.Second
+)
+
+// ErrNotAudio is returned for links that are neither audio nor a playlist
+var ErrNotAudio = errors.New("radio: link is not an audio stream")
+
+// audioExtensions are the file types of audio files that have an end
+var audioExtensions = map[string]bool{
+	".aac":  true,
+	".flac": true,
+	".m4a":  true,
+	".mp3":  true,
+	".ogg":  true,
+	".opus": true,
+	".wav":  true,
+	".webm": true,
+}
+
+// Stream is an audio file or a live stream
+type Stream struct {
+	URL   string
+	Title string
+	// Duration is the length of the stream in seconds, 0 if unknown
+	Duration float64
+	// Live streams do not end, such as internet radio stations
+	Live bool
+}
+
+// Radio looks up audio links and radio playlists
+type Radio struct {
+	client *http.Client
+	// allowPrivate disables the checks for private addresses, for tests
+	allowPrivate bool
+}
+
+// NewRadio returns a radio that only fetches links to public addresses
+func NewRadio() *Radio {
+	return &Radio{client: newClient()}
+}
+
+// GetStreams returns the stream of an audio link, or the streams listed in
+// an M3U or PLS playlist
+func (r *Radio) GetStreams(link string) ([]*Stream, error) {
+	logger.Log.Debug("radio.GetStreams")
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request, err=%w", err)
+	}
+	// Ask for metadata to find out whether the server is an icecast or
+	// shoutcast station
+	req.Header.Set("Icy-MetaData", "1")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch %s, err=%w", link, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch %s, status=%s", link, resp.Status)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	ext := strings.ToLower(path.Ext(resp.Request.URL.Path))
+	switch {
+	case contentType == "audio/x-scpls" || contentType == "application/pls+xml" || ext == ".pls":
+		entries, err := ParsePLS(io.LimitReader(resp.Body, maxPlaylistSize))
+		if err != nil {
+			return nil, err
+		}
+		return r.playlistStreams(resp.Request.URL, entries), nil
+	case contentType == "audio/x-mpegurl" || contentType == "audio/mpegurl" || ext == ".m3u":
+		entries, err := ParseM3U(io.LimitReader(resp.Body, maxPlaylistSize))
+		if err != nil {
+			return nil, err
+		}
+		return r.playlistStreams(resp.Request.URL, entries), nil
+	case strings.HasPrefix(contentType, "audio/") || contentType == "application/ogg" || isIcy(resp.Header):
+		// The stream is played from where the link redirected to, which the
+		// client has checked
+		stream := &Stream{URL: resp.Request.URL.String(), Title: titleFromURL(resp.Request.URL)}
+		if name := resp.Header.Get("icy-name"); name != "" {
+			stream.Title = name
+		}
+		stream.Live = isIcy(resp.Header) || resp.ContentLength < 0 && !audioExtensions[ext]
+		return []*Stream{stream}, nil
+	}
+	return nil, ErrNotAudio
+}
+
+// isIcy returns whether the response is from an icecast or shoutcast station
+func isIcy(header http.Header) bool {
+	return header.Get("icy-metaint") != "" || header.Get("icy-name") != "" || header.Get("icy-br") != ""
+}
+
+// playlistStreams returns the streams of playlist entries, links relative to
+// the playlist are resolved. Only the first maxPlaylistEntries streams are
+// returned, entries with private addresses are left out.
+func (r *Radio) playlistStreams(base *url.URL, entries []Entry) []*Stream {
+	streams := make([]*Stream, 0, min(len(entries), maxPlaylistEntries))
+	// Playlists usually list a handful of hosts, each is looked up once
+	checked := make(map[string]error)
+	for _, entry := range entries {
+		if len(streams) == maxPlaylistEntries {
+			logger.Log.Warningf("playlist %s has more than %d entries, skipping the rest", base, maxPlaylistEntries)
+			break
+		}
+		link, err := base.Parse(entry.URL)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			logger.Log.Warningf("skipping playlist entry %s, err=%v", entry.URL, err)
+			continue
+		}
+		err, ok := checked[link.Host]
+		if !ok {
+			err = r.CheckLink(link.String())
+			checked[link.Host] = err
+		}
+		if err != nil {
+			logger.Log.Warningf("skipping playlist entry %s, err=%v", entry.URL, err)
+			continue
+		}
+		stream := &Stream{URL: link.String(), Title: entry.Title}
+		if stream.Title == "" {
+			stream.Title = titleFromURL(link)
+		}
+		switch {
+		case entry.Duration > 0:
+			stream.Duration = entry.Duration
+		case entry.Duration < 0:
+			stream.Live = true
+		default:
+			stream.Live = !audioExtensions[strings.ToLower(path.Ext(link.Path))]
+		}
+		streams = append(streams, stream)
+	}
+	return streams
+}
+
+// titleFromURL returns the file name of a link, or its host if it has none
+func titleFromURL(link *url.URL) string {
+	name := path.Base(link.Path)
+	if name == "/" || name == "." {
+		return link.Host
+	}
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
+
+// WatchTitle reads the ICY metadata of a live stream and calls update with
+// the title of the stream whenever it changes, until the context is done or
+// the stream ends
+func (r *Radio) WatchTitle(ctx context.Context, link string, update func(title string)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return fmt.Errorf("could not create request, err=%w", err)
+	}
+	req.Header.Set("Icy-MetaData", "1")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not fetch %s, err=%w", link, err)
+	}
+	defer resp.Body.Close()
+	metaint, err := strconv.Atoi(resp.Header.Get("icy-metaint"))
+	if err != nil || metaint <= 0 {
+		return fmt.Errorf("stream %s has no metadata", link)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	title := ""
+	for {
+		// Metadata is sent after every metaint bytes of audio
+		if _, err := body.Discard(metaint); err != nil {
+			return streamEnded(ctx, err)
+		}
+		length, err := body.ReadByte()
+		if err != nil {
+			return streamEnded(ctx, err)
+		}
+		if length == 0 {
+			continue
+		}
+		metadata := make([]byte, int(length)*16)
+		if _, err := io.ReadFull(body, metadata); err != nil {
+			return streamEnded(ctx, err)
+		}
+		next, ok := ParseStreamTitle(string(metadata))
+		if ok && next != title {
+			title = next
+			update(title)
+		}
+	}
+}
+
+// streamEnded returns the error that stopped reading a stream, nil if the
+// stream was stopped by the context
+func streamEnded(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("could not read stream metadata, err=%w", err)
+}
+
+// ParseStreamTitle returns the StreamTitle of an ICY metadata block, such as
+// StreamTitle='Artist - Title';StreamUrl='http://example.com';
+func ParseStreamTitle(metadata string) (string, bool) {
+	metadata = strings.TrimRight(metadata, "\x00")
+	const key = "StreamTitle='"
+	start := strings.Index(metadata, key)
+	if start < 0 {
+		return "", false
+	}
+	value := metadata[start+len(key):]
+	// The title can contain quotes, so it ends at the quote before the
+	// separator of the next field rather than the first quote
+	end := strings.Index(value, "';")
+	if end < 0 {
+		end = strings.LastIndex(value, "'")
+	}
+	if end < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(value[:end]), true
+}
@@ -0,0 +1,225 @@
+package radio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// metaint is how many bytes of audio the stub station sends between metadata
+const metaint = 32
+
+// icyBlock returns an ICY metadata block, padded to a multiple of 16 bytes
+func icyBlock(metadata string) []byte {
+	blocks := (len(metadata) + 15) / 16
+	block := make([]byte, 1+blocks*16)
+	block[0] = byte(blocks)
+	copy(block[1:], metadata)
+	return block
+}
+
+// newStubServer returns a server with audio files, playlists and a radio
+// station sending the given stream titles
+func newStubServer(t *testing.T, titles []string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/song.mp3", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("Content-Length", "4")
+		_, _ = w.Write([]byte("song"))
+	})
+	mux.Handle("/old.mp3", http.RedirectHandler("/song.mp3", http.StatusFound))
+	mux.HandleFunc("/radio.m3u", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/x-mpegurl")
+		_, _ = w.Write([]byte("#EXTM3U\n#EXTINF:-1,Radio Surbot\n/live\n#EXTINF:60,Song\nsong.mp3\nftp://example.com/song.mp3\n"))
+	})
+	mux.HandleFunc("/listen.pls", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("[playlist]\nFile1=/live\nTitle1=Radio Surbot\nLength1=-1\n"))
+	})
+	mux.HandleFunc("/public.m3u", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/x-mpegurl")
+		_, _ = w.Write([]byte("/live\nhttp://169.254.169.254/latest/meta-data\n"))
+		for i := 0; i < maxPlaylistEntries+10; i++ {
+			_, _ = fmt.Fprintf(w, "http://93.184.216.34/song%d.mp3\n", i)
+		}
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html></html>"))
+	})
+	mux.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("icy-name", "Radio Surbot")
+		if r.Header.Get("Icy-MetaData") != "1" {
+			return
+		}
+		w.Header().Set("icy-metaint", strconv.Itoa(metaint))
+		audio := make([]byte, metaint)
+		for _, title := range titles {
+			_, _ = w.Write(audio)
+			_, _ = w.Write(icyBlock("StreamTitle='" + title + "';StreamUrl='';"))
+			_, _ = w.Write(audio)
+			_, _ = w.Write([]byte{0})
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRadio_GetStreams(t *testing.T) {
+	server := newStubServer(t, nil)
+	radio := &Radio{client: server.Client(), allowPrivate: true}
+
+	tests := []struct {
+		path    string
+		want    []*Stream
+		wantErr error
+	}{
+		{path: "/song.mp3", want: []*Stream{{URL: server.URL + "/song.mp3", Title: "song.mp3"}}},
+		{path: "/old.mp3", want: []*Stream{{URL: server.URL + "/song.mp3", Title: "song.mp3"}}},
+		{path: "/live", want: []*Stream{{URL: server.URL + "/live", Title: "Radio Surbot", Live: true}}},
+		{path: "/radio.m3u", want: []*Stream{
+			{URL: server.URL + "/live", Title: "Radio Surbot", Live: true},
+			{URL: server.URL + "/song.mp3", Title: "Song", Duration: 60},
+		}},
+		{path: "/listen.pls", want: []*Stream{{URL: server.URL + "/live", Title: "Radio Surbot", Live: true}}},
+		{path: "/page", wantErr: ErrNotAudio},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := radio.GetStreams(server.URL + tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetStreams() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetStreams() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if _, err := radio.GetStreams(server.URL + "/missing"); err == nil {
+		t.Error("GetStreams() of a missing page did not return an error")
+	}
+}
+
+func TestRadio_Private(t *testing.T) {
+	server := newStubServer(t, nil)
+
+	_, err := NewRadio().GetStreams(server.URL + "/song.mp3")
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("GetStreams() error = %v, want %v", err, ErrPrivateAddress)
+	}
+
+	// The stub client reaches the test server, the entries are still checked
+	radio := &Radio{client: server.Client()}
+	streams, err := radio.GetStreams(server.URL + "/public.m3u")
+	if err != nil {
+		t.Fatalf("GetStreams() error = %v", err)
+	}
+	if len(streams) != maxPlaylistEntries {
+		t.Fatalf("GetStreams() returned %d streams, want %d", len(streams), maxPlaylistEntries)
+	}
+	if want := "http://93.184.216.34/song0.mp3"; streams[0].URL != want {
+		t.Errorf("GetStreams() first stream = %s, want %s", streams[0].URL, want)
+	}
+}
+
+func TestRadio_FollowLink(t *testing.T) {
+	server := newStubServer(t, nil)
+	radio := &Radio{client: server.Client(), allowPrivate: true}
+
+	got, err := radio.FollowLink(server.URL + "/old.mp3")
+	if err != nil {
+		t.Fatalf("FollowLink() error = %v", err)
+	}
+	if want := server.URL + "/song.mp3"; got != want {
+		t.Errorf("FollowLink() = %s, want %s", got, want)
+	}
+	if _, err := radio.FollowLink(server.URL + "/missing"); err == nil {
+		t.Error("FollowLink() of a missing page did not return an error")
+	}
+
+	// The link the stub client ends up at is checked as well
+	radio = &Radio{client: server.Client()}
+	if _, err := radio.FollowLink(server.URL + "/old.mp3"); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("FollowLink() error = %v, want %v", err, ErrPrivateAddress)
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{addr: "127.0.0.1", want: false},
+		{addr: "10.96.0.1", want: false},
+		{addr: "172.16.5.4", want: false},
+		{addr: "192.168.1.1", want: false},
+		{addr: "169.254.169.254", want: false},
+		{addr: "100.100.100.200", want: false},
+		{addr: "0.0.0.0", want: false},
+		{addr: "::1", want: false},
+		{addr: "::ffff:127.0.0.1", want: false},
+		{addr: "fd00:ec2::254", want: false},
+		{addr: "fe80::1", want: false},
+		{addr: "224.0.0.1", want: false},
+	}
+	for _, tt := range tests {
+		if got := publicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestRadio_WatchTitle(t *testing.T) {
+	server := newStubServer(t, []string{"Artist - First", "Artist - First", "It's the 'second' song"})
+	radio := &Radio{client: server.Client(), allowPrivate: true}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var titles []string
+	err := radio.WatchTitle(ctx, server.URL+"/live", func(title string) {
+		titles = append(titles, title)
+	})
+	if err == nil || !strings.Contains(err.Error(), "EOF") {
+		t.Errorf("WatchTitle() error = %v, want the end of the stream", err)
+	}
+	if want := []string{"Artist - First", "It's the 'second' song"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("WatchTitle() titles = %v, want %v", titles, want)
+	}
+
+	if err := radio.WatchTitle(ctx, server.URL+"/song.mp3", func(string) {}); err == nil {
+		t.Error("WatchTitle() of a stream without metadata did not return an error")
+	}
+}
+
+func TestParseStreamTitle(t *testing.T) {
+	tests := []struct {
+		metadata string
+		want     string
+		wantOk   bool
+	}{
+		{metadata: "StreamTitle='Artist - Title';StreamUrl='http://example.com';\x00\x00", want: "Artist - Title", wantOk: true},
+		{metadata: "StreamTitle='Don't stop';", want: "Don't stop", wantOk: true},
+		{metadata: "StreamTitle='No separator'\x00", want: "No separator", wantOk: true},
+		{metadata: "StreamTitle='';", want: "", wantOk: true},
+		{metadata: "StreamUrl='http://example.com';", wantOk: false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseStreamTitle(tt.metadata)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("ParseStreamTitle(%q) = %q, %v, want %q, %v", tt.metadata, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
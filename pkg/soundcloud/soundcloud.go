@@ -0,0 +1,392 @@
+// Package soundcloud provides soundcloud playback functionality.
+package soundcloud
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/sajfer/surbot/internal/logger"
+)
+
+const (
+	siteURL = "https://soundcloud.com"
+	apiURL  = "https://api-v2.soundcloud.com"
+	// maxTrackIDs is how many tracks can be looked up in one request
+	maxTrackIDs = 50
+	// streamTTL is how long a stream is assumed to work when its link does
+	// not say when it expires
+	streamTTL = 30 * time.Minute
+	// requestTimeout is how long a request to soundcloud may take
+	requestTimeout = 15 * time.Second
+)
+
+var (
+	// scriptRegex finds the scripts of the soundcloud web app, one of which
+	// contains the client ID of the app
+	scriptRegex   = regexp.MustCompile(`<script crossorigin src="([^"]+\.js)"`)
+	clientIDRegex = regexp.MustCompile(`[^_]client_id\s*:\s*"([a-zA-Z0-9]{32})"`)
+
+	errUnauthorized = errors.New("soundcloud: client ID was rejected")
+)
+
+// Soundcloud looks up tracks and sets using the API of the soundcloud web
+// app, with the client ID the web app uses
+type Soundcloud struct {
+	client   *http.Client
+	siteURL  string
+	apiURL   string
+	mu       sync.Mutex
+	clientID string
+	// lookup is the lookup of the client ID in progress, if any
+	lookup *clientIDLookup
+}
+
+// clientIDLookup is a lookup of the client ID that concurrent requests wait for
+type clientIDLookup struct {
+	done     chan struct{}
+	clientID string
+	err      error
+}
+
+// Track is a soundcloud track
+type Track struct {
+	ID        int
+	Title     string
+	Artist    string
+	Duration  float64
+	Thumbnail string
+	URL       string
+}
+
+// Playlist is a soundcloud set, or a single track
+type Playlist struct {
+	Title    string
+	Uploader string
+	Tracks   []*Track
+}
+
+type user struct {
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type transcoding struct {
+	URL    string `json:"url"`
+	Format struct {
+		Protocol string `json:"protocol"`
+		MimeType string `json:"mime_type"`
+	} `json:"format"`
+}
+
+type apiTrack struct {
+	Kind         string `json:"kind"`
+	ID           int    `json:"id"`
+	Title        string `json:"title"`
+	Duration     int    `json:"duration"`
+	ArtworkURL   string `json:"artwork_url"`
+	PermalinkURL string `json:"permalink_url"`
+	User         user   `json:"user"`
+	Media        struct {
+		Transcodings []transcoding `json:"transcodings"`
+	} `json:"media"`
+	TrackAuthorization string `json:"track_authorization"`
+}
+
+type apiResource struct {
+	apiTrack
+	Tracks []apiTrack `json:"tracks"`
+}
+
+func NewSoundcloud() *Soundcloud {
+	return &Soundcloud{client: &http.Client{Timeout: requestTimeout}, siteURL: siteURL, apiURL: apiURL}
+}
+
+// GetTracks returns the tracks of a track or set link
+func (s *Soundcloud) GetTracks(link string) (*Playlist, error) {
+	logger.Log.Debug("soundcloud.GetTracks")
+	if !strings.HasPrefix(link, "http") {
+		link = "https://" + link
+	}
+
+	var resource apiResource
+	err := s.get("/resolve", url.Values{"url": {link}}, &resource)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %s, err=%w", link, err)
+	}
+	switch resource.Kind {
+	case "track":
+		return &Playlist{Tracks: []*Track{newTrack(&resource.apiTrack)}}, nil
+	case "playlist":
+		tracks, err := s.completeTracks(resource.Tracks)
+		if err != nil {
+			return nil, err
+		}
+		playlist := &Playlist{Title: resource.Title, Uploader: resource.User.Username}
+		for i := range tracks {
+			playlist.Tracks = append(playlist.Tracks, newTrack(&tracks[i]))
+		}
+		return playlist, nil
+	}
+	return nil, fmt.Errorf("%s is a %s, not a track or set", link, resource.Kind)
+}
+
+// completeTracks looks up the tracks of a set that were only returned as IDs,
+// which the API does for all but the first few tracks of a set
+func (s *Soundcloud) completeTracks(tracks []apiTrack) ([]apiTrack, error) {
+	var missing []string
+	for _, track := range tracks {
+		if track.Title == "" {
+			missing = append(missing, strconv.Itoa(track.ID))
+		}
+	}
+	full := map[int]apiTrack{}
+	for start := 0; start < len(missing); start += maxTrackIDs {
+		end := start + maxTrackIDs
+		if end > len(missing) {
+			end = len(missing)
+		}
+		var page []apiTrack
+		err := s.get("/tracks", url.Values{"ids": {strings.Join(missing[start:end], ",")}}, &page)
+		if err != nil {
+			return nil, fmt.Errorf("could not look up tracks of set, err=%w", err)
+		}
+		for _, track := range page {
+			full[track.ID] = track
+		}
+	}
+
+	complete := make([]apiTrack, 0, len(tracks))
+	for _, track := range tracks {
+		if track.Title == "" {
+			found, ok := full[track.ID]
+			if !ok {
+				// The track has been removed or made private
+				continue
+			}
+			track = found
+		}
+		complete = append(complete, track)
+	}
+	return complete, nil
+}
+
+// GetStreamURL returns a link to the audio of a track and when it expires
+func (s *Soundcloud) GetStreamURL(id int) (string, time.Time, error) {
+	logger.Log.Debug("soundcloud.GetStreamURL")
+	var track apiTrack
+	err := s.get("/tracks/"+strconv.Itoa(id), nil, &track)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("could not look up track %d, err=%w", id, err)
+	}
+
+	// Progressive streams are plain mp3 files, hls streams are used for
+	// tracks that only have those
+	var chosen *transcoding
+	for i, transcoding := range track.Media.Transcodings {
+		if transcoding.Format.Protocol == "progressive" {
+			chosen = &track.Media.Transcodings[i]
+			break
+		}
+		if transcoding.Format.Protocol == "hls" && chosen == nil {
+			chosen = &track.Media.Transcodings[i]
+		}
+	}
+	if chosen == nil {
+		return "", time.Time{}, fmt.Errorf("track %d has no playable stream", id)
+	}
+
+	var stream struct {
+		URL string `json:"url"`
+	}
+	params := url.Values{}
+	if track.TrackAuthorization != "" {
+		params.Set("track_authorization", track.TrackAuthorization)
+	}
+	err = s.get(chosen.URL, params, &stream)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("could not look up stream of track %d, err=%w", id, err)
+	}
+	return stream.URL, streamExpiry(stream.URL, time.Now()), nil
+}
+
+// streamExpiry returns when a stream expires. The links are signed by
+// cloudfront, either with an Expires parameter or with a policy containing
+// the expiry. Links without an expiry in the future are assumed to work for
+// streamTTL.
+func streamExpiry(stream string, now time.Time) time.Time {
+	parsed, err := url.Parse(stream)
+	if err != nil {
+		return now.Add(streamTTL)
+	}
+	query := parsed.Query()
+	expires, err := strconv.ParseInt(query.Get("Expires"), 10, 64)
+	if err != nil {
+		expires = policyExpiry(query.Get("Policy"))
+	}
+	if expiry := time.Unix(expires, 0); expires > 0 && expiry.After(now) {
+		return expiry
+	}
+	return now.Add(streamTTL)
+}
+
+// policyExpiry returns the expiry of a cloudfront policy, 0 if it has none
+func policyExpiry(policy string) int64 {
+	// Cloudfront replaces the characters of base64 that are not safe in links
+	decoded, err := base64.StdEncoding.DecodeString(strings.NewReplacer("-", "+", "_", "=", "~", "/").Replace(policy))
+	if err != nil {
+		return 0
+	}
+	var statements struct {
+		Statement []struct {
+			Condition struct {
+				DateLessThan struct {
+					EpochTime int64 `json:"AWS:EpochTime"`
+				} `json:"DateLessThan"`
+			} `json:"Condition"`
+		} `json:"Statement"`
+	}
+	if json.Unmarshal(decoded, &statements) != nil || len(statements.Statement) == 0 {
+		return 0
+	}
+	return statements.Statement[0].Condition.DateLessThan.EpochTime
+}
+
+// newTrack returns a track of the API, the artwork of the uploader is used
+// for tracks without artwork
+func newTrack(track *apiTrack) *Track {
+	thumbnail := track.ArtworkURL
+	if thumbnail == "" {
+		thumbnail = track.User.AvatarURL
+	}
+	return &Track{
+		ID:        track.ID,
+		Title:     track.Title,
+		Artist:    track.User.Username,
+		Duration:  float64(track.Duration) / 1000,
+		Thumbnail: strings.Replace(thumbnail, "-large.", "-t500x500.", 1),
+		URL:       track.PermalinkURL,
+	}
+}
+
+// get decodes the response of an API endpoint, endpoint is either a path or
+// a link returned by the API. The client ID is looked up again if it has
+// been rotated.
+func (s *Soundcloud) get(endpoint string, params url.Values, v interface{}) error {
+	err := s.getOnce(endpoint, params, v)
+	if errors.Is(err, errUnauthorized) {
+		s.mu.Lock()
+		s.clientID = ""
+		s.mu.Unlock()
+		err = s.getOnce(endpoint, params, v)
+	}
+	return err
+}
+
+func (s *Soundcloud) getOnce(endpoint string, params url.Values, v interface{}) error {
+	clientID, err := s.getClientID()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(endpoint, "http") {
+		endpoint = s.apiURL + endpoint
+	}
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("client_id", clientID)
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
+	}
+	resp, err := s.client.Get(endpoint + separator + params.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return errUnauthorized
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("could not decode response, err=%w", err)
+	}
+	return nil
+}
+
+// getClientID returns the client ID of the soundcloud web app, it is looked
+// up in the scripts of the web app the first time it is needed. Requests made
+// during the lookup wait for it instead of looking it up again
+func (s *Soundcloud) getClientID() (string, error) {
+	s.mu.Lock()
+	if s.clientID != "" {
+		defer s.mu.Unlock()
+		return s.clientID, nil
+	}
+	if lookup := s.lookup; lookup != nil {
+		s.mu.Unlock()
+		<-lookup.done
+		return lookup.clientID, lookup.err
+	}
+	lookup := &clientIDLookup{done: make(chan struct{})}
+	s.lookup = lookup
+	s.mu.Unlock()
+
+	lookup.clientID, lookup.err = s.findClientID()
+	s.mu.Lock()
+	s.clientID = lookup.clientID
+	s.lookup = nil
+	s.mu.Unlock()
+	close(lookup.done)
+	return lookup.clientID, lookup.err
+}
+
+// findClientID looks up the client ID in the scripts of the web app
+func (s *Soundcloud) findClientID() (string, error) {
+	page, err := s.fetch(s.siteURL)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch soundcloud, err=%w", err)
+	}
+	scripts := scriptRegex.FindAllStringSubmatch(page, -1)
+	// The client ID is in one of the last scripts, so they are searched backwards
+	for i := len(scripts) - 1; i >= 0; i-- {
+		script, err := s.fetch(scripts[i][1])
+		if err != nil {
+			logger.Log.Warningf("could not fetch soundcloud script, err=%v", err)
+			continue
+		}
+		if match := clientIDRegex.FindStringSubmatch(script); match != nil {
+			return match[1], nil
+		}
+	}
+	return "", errors.New("could not find soundcloud client ID")
+}
+
+// fetch returns the body of a page
+func (s *Soundcloud) fetch(link string) (string, error) {
+	resp, err := s.client.Get(link)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
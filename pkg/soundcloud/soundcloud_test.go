@@ -0,0 +1,228 @@
+package soundcloud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testClientID = "aB3dE6gH9jK2mN5pQ8sT1vW4yZ7bC0eF"
+
+// newStubServer serves the recorded responses in testdata, with the links to
+// soundcloud in them pointing to the stub
+func newStubServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	fixture := func(w http.ResponseWriter, name string) {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := strings.NewReplacer(
+			"https://a-v2.sndcdn.com", server.URL,
+			"https://api-v2.soundcloud.com", server.URL,
+		).Replace(string(data))
+		_, _ = w.Write([]byte(body))
+	}
+	api := func(handler func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("client_id") != testClientID {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			handler(w, r)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) { fixture(w, "site.html") })
+	mux.HandleFunc("/assets/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/assets/")
+		if _, err := os.Stat(filepath.Join("testdata", name)); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		fixture(w, name)
+	})
+	mux.HandleFunc("/resolve", api(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("url") {
+		case "https://soundcloud.com/surbot-artist/first-track":
+			fixture(w, "resolve_track.json")
+		case "https://soundcloud.com/surbot-artist/sets/mixtape":
+			fixture(w, "resolve_set.json")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	mux.HandleFunc("/tracks", api(func(w http.ResponseWriter, r *http.Request) {
+		if ids := r.URL.Query().Get("ids"); ids != "1002,1003,1004" {
+			t.Errorf("looked up tracks %s, want %s", ids, "1002,1003,1004")
+		}
+		fixture(w, "tracks.json")
+	}))
+	mux.HandleFunc("/tracks/1001", api(func(w http.ResponseWriter, r *http.Request) { fixture(w, "resolve_track.json") }))
+	mux.HandleFunc("/media/soundcloud:tracks:1001/1a2b3c/stream/progressive", api(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.URL.Query().Get("track_authorization"); auth != "eyJ0eXAiOiJKV1QifQ.track1001" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fixture(w, "stream.json")
+	}))
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestSoundcloud(server *httptest.Server) *Soundcloud {
+	return &Soundcloud{client: server.Client(), siteURL: server.URL, apiURL: server.URL}
+}
+
+func TestSoundcloud_GetTracks(t *testing.T) {
+	server := newStubServer(t)
+	first := &Track{
+		ID:        1001,
+		Title:     "First Track",
+		Artist:    "Surbot Artist",
+		Duration:  215.434,
+		Thumbnail: "https://i1.sndcdn.com/artworks-000123456789-abcdef-t500x500.jpg",
+		URL:       "https://soundcloud.com/surbot-artist/first-track",
+	}
+
+	tests := []struct {
+		name    string
+		link    string
+		want    *Playlist
+		wantErr bool
+	}{
+		{name: "track", link: "https://soundcloud.com/surbot-artist/first-track", want: &Playlist{Tracks: []*Track{first}}},
+		{name: "without scheme", link: "soundcloud.com/surbot-artist/first-track", want: &Playlist{Tracks: []*Track{first}}},
+		{
+			name: "set",
+			link: "https://soundcloud.com/surbot-artist/sets/mixtape",
+			want: &Playlist{Title: "Mixtape", Uploader: "Surbot Artist", Tracks: []*Track{
+				first,
+				{
+					ID:        1002,
+					Title:     "Second Track",
+					Artist:    "Surbot Artist",
+					Duration:  168,
+					Thumbnail: "https://i1.sndcdn.com/artworks-000987654321-fedcba-t500x500.jpg",
+					URL:       "https://soundcloud.com/surbot-artist/second-track",
+				},
+				{
+					ID:        1003,
+					Title:     "Third Track",
+					Artist:    "Guest",
+					Duration:  180,
+					Thumbnail: "https://i1.sndcdn.com/avatars-000222-bbbbbb-t500x500.jpg",
+					URL:       "https://soundcloud.com/guest/third-track",
+				},
+			}},
+		},
+		{name: "missing", link: "https://soundcloud.com/surbot-artist/missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestSoundcloud(server).GetTracks(tt.link)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetTracks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetTracks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSoundcloud_GetStreamURL(t *testing.T) {
+	server := newStubServer(t)
+	soundcloud := newTestSoundcloud(server)
+	// A client ID that has been rotated is looked up again
+	soundcloud.clientID = "rotated"
+
+	got, expiry, err := soundcloud.GetStreamURL(1001)
+	if err != nil {
+		t.Fatalf("GetStreamURL() error = %v", err)
+	}
+	want := "https://cf-media.sndcdn.com/AbCdEfGh1234.128.mp3?Policy=eyJTdGF0ZW1lbnQiOlt7IlJlc291cmNlIjoiKjovL2NmLW1lZGlhLnNuZGNkbi5jb20vQWJDZEVmR2gxMjM0LjEyOC5tcDMqIiwiQ29uZGl0aW9uIjp7IkRhdGVMZXNzVGhhbiI6eyJBV1M6RXBvY2hUaW1lIjo0MTAyNDQ0ODAwfX19XX0_&Signature=abc~def&Key-Pair-Id=APKAI6TU7MMXM5DG6EPQ"
+	if got != want {
+		t.Errorf("GetStreamURL() = %s, want %s", got, want)
+	}
+	if want := time.Unix(4102444800, 0); !expiry.Equal(want) {
+		t.Errorf("GetStreamURL() expiry = %v, want %v", expiry, want)
+	}
+	if soundcloud.clientID != testClientID {
+		t.Errorf("client ID = %s, want %s", soundcloud.clientID, testClientID)
+	}
+
+	if _, _, err := soundcloud.GetStreamURL(1002); err == nil {
+		t.Error("GetStreamURL() of a missing track did not return an error")
+	}
+}
+
+// pageCounter counts the requests for the front page of soundcloud
+type pageCounter struct {
+	pages atomic.Int32
+}
+
+func (c *pageCounter) RoundTrip(r *http.Request) (*http.Response, error) {
+	if strings.Trim(r.URL.Path, "/") == "" {
+		c.pages.Add(1)
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestSoundcloud_ConcurrentClientID(t *testing.T) {
+	server := newStubServer(t)
+	soundcloud := newTestSoundcloud(server)
+	counter := &pageCounter{}
+	soundcloud.client = &http.Client{Transport: counter}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clientID, err := soundcloud.getClientID()
+			if err != nil || clientID != testClientID {
+				t.Errorf("getClientID() = %s, error = %v, want %s", clientID, err, testClientID)
+			}
+		}()
+	}
+	wg.Wait()
+	if pages := counter.pages.Load(); pages != 1 {
+		t.Errorf("client ID was looked up %d times, want %d", pages, 1)
+	}
+}
+
+func TestStreamExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	// {"Statement":[{"Condition":{"DateLessThan":{"AWS:EpochTime":1700003600}}}]}
+	policy := "eyJTdGF0ZW1lbnQiOlt7IkNvbmRpdGlvbiI6eyJEYXRlTGVzc1RoYW4iOnsiQVdTOkVwb2NoVGltZSI6MTcwMDAwMzYwMH19fV19"
+	tests := []struct {
+		name   string
+		stream string
+		want   time.Time
+	}{
+		{name: "policy", stream: "https://cf-media.sndcdn.com/a.128.mp3?Policy=" + policy + "&Signature=abc", want: time.Unix(1700003600, 0)},
+		{name: "expires", stream: "https://cf-hls-media.sndcdn.com/playlist/a.128.mp3/playlist.m3u8?Expires=1700001800&Signature=abc", want: time.Unix(1700001800, 0)},
+		{name: "expired", stream: "https://cf-media.sndcdn.com/a.128.mp3?Expires=1699990000", want: now.Add(streamTTL)},
+		{name: "empty policy", stream: "https://cf-media.sndcdn.com/a.128.mp3?Policy=eyJTdGF0ZW1lbnQiOltdfQ__", want: now.Add(streamTTL)},
+		{name: "invalid policy", stream: "https://cf-media.sndcdn.com/a.128.mp3?Policy=%21%21", want: now.Add(streamTTL)},
+		{name: "no expiry", stream: "https://cf-media.sndcdn.com/a.128.mp3", want: now.Add(streamTTL)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := streamExpiry(tt.stream, now); !got.Equal(tt.want) {
+				t.Errorf("streamExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
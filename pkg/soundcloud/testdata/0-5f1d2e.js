@@ -0,0 +1 @@
+(window.webpackJsonp=window.webpackJsonp||[]).push([[0],{7:function(e,t,n){"use strict";var r=n(12),o={env:"production",public_api_client_id:"notTheClientIdOfTheWebAppAtAll0"};e.exports=o}}]);
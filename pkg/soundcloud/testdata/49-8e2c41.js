@@ -0,0 +1 @@
+(window.webpackJsonp=window.webpackJsonp||[]).push([[49],{1022:function(e,t,n){"use strict";n.r(t);var r={api_v2_host:"api-v2.soundcloud.com",client_id:"aB3dE6gH9jK2mN5pQ8sT1vW4yZ7bC0eF",env:"production"};t.default=r}}]);
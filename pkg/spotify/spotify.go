@@ -3,6 +3,7 @@ package spotifyClient
 
 import (
 	"context"
+	"errors"
 	"log"
 	"time"
 
@@ -14,15 +15,22 @@ import (
 	"golang.org/x/oauth2/clientcredentials"
 )
 
+// DefaultMaxTracks is the number of tracks read from a playlist or album
+// unless another limit is set
+const DefaultMaxTracks = 500
+
 type Client struct {
-	client *spotify.Client
-	token  *oauth2.Token
-	config *clientcredentials.Config
+	client    *spotify.Client
+	token     *oauth2.Token
+	config    *clientcredentials.Config
+	maxTracks int
 }
 
 type Song struct {
 	Artist string
 	Name   string
+	// Duration is the length of the track in seconds
+	Duration float64
 }
 
 type Playlist struct {
@@ -41,7 +49,13 @@ func NewSpotifyClient(clientID, clientSecret string) *Client {
 
 	client, token := newClient(config)
 
-	return &Client{client: client, token: token, config: config}
+	return &Client{client: client, token: token, config: config, maxTracks: DefaultMaxTracks}
+}
+
+// SetMaxTracks sets how many tracks are read from a playlist or album, longer
+// ones are truncated
+func (c *Client) SetMaxTracks(maxTracks int) {
+	c.maxTracks = maxTracks
 }
 
 func newClient(config *clientcredentials.Config) (*spotify.Client, *oauth2.Token) {
@@ -88,38 +102,77 @@ func (c *Client) GetTrack(query string) (*Playlist, error) {
 		logger.Log.Warningf("Could not search for spotify track, err=%v", err)
 		return &Playlist{}, err
 	}
-	logger.Log.Debugf("%s", results.SimpleTrack.Name)                                                                   //nolint:all
-	return &Playlist{Songs: []Song{{Name: results.SimpleTrack.Name, Artist: results.SimpleTrack.Artists[0].Name}}}, nil //nolint:all
+	logger.Log.Debugf("%s", results.SimpleTrack.Name) //nolint:all
+	track := results.SimpleTrack                      //nolint:all
+	return &Playlist{Songs: []Song{{Name: track.Name, Artist: track.Artists[0].Name, Duration: track.TimeDuration().Seconds()}}}, nil
 }
 
 func (c *Client) GetPlaylist(query string) (*Playlist, error) {
 	logger.Log.Debug("spotify.GetPlaylist")
 	ctx := context.Background()
 	c.checkToken()
-	results, err := c.client.GetPlaylist(ctx, spotify.ID(query), spotify.Limit(1))
+	results, err := c.client.GetPlaylist(ctx, spotify.ID(query))
 	if err != nil {
 		logger.Log.Warningf("Could not search for spotify track, err=%v", err)
 		return &Playlist{}, err
 	}
 	playlist := &Playlist{Title: results.Name, Uploader: results.SimplePlaylist.Owner.DisplayName} //nolint:all
-	for _, item := range results.Tracks.Tracks {
-		playlist.Songs = append(playlist.Songs, Song{Name: item.Track.Name, Artist: item.Track.Artists[0].Name})
+	tracks := &results.Tracks
+	for {
+		for _, item := range tracks.Tracks {
+			if len(playlist.Songs) >= c.maxTracks {
+				return playlist, nil
+			}
+			playlist.Songs = appendSong(playlist.Songs, item.Track.SimpleTrack)
+		}
+		err = c.client.NextPage(ctx, tracks)
+		if errors.Is(err, spotify.ErrNoMorePages) {
+			return playlist, nil
+		}
+		if err != nil {
+			logger.Log.Warningf("Could not get next page of spotify playlist, err=%v", err)
+			return playlist, err
+		}
 	}
-	return playlist, nil
 }
 
 func (c *Client) GetAlbum(query string) (*Playlist, error) {
 	logger.Log.Debug("spotify.GetAlbum")
 	ctx := context.Background()
 	c.checkToken()
-	results, err := c.client.GetAlbum(ctx, spotify.ID(query), spotify.Limit(1))
+	results, err := c.client.GetAlbum(ctx, spotify.ID(query))
 	if err != nil {
 		logger.Log.Warningf("Could not search for spotify track, err=%v", err)
 		return &Playlist{}, err
 	}
 	playlist := &Playlist{}
-	for _, item := range results.Tracks.Tracks {
-		playlist.Songs = append(playlist.Songs, Song{Name: item.Name, Artist: item.Artists[0].Name})
+	tracks := &results.Tracks
+	for {
+		for _, item := range tracks.Tracks {
+			if len(playlist.Songs) >= c.maxTracks {
+				return playlist, nil
+			}
+			playlist.Songs = appendSong(playlist.Songs, item)
+		}
+		err = c.client.NextPage(ctx, tracks)
+		if errors.Is(err, spotify.ErrNoMorePages) {
+			return playlist, nil
+		}
+		if err != nil {
+			logger.Log.Warningf("Could not get next page of spotify album, err=%v", err)
+			return playlist, err
+		}
+	}
+}
+
+// appendSong appends a track to songs, skipping unavailable tracks without a name
+func appendSong(songs []Song, track spotify.SimpleTrack) []Song {
+	if track.Name == "" {
+		return songs
+	}
+	song := Song{Name: track.Name, Duration: track.TimeDuration().Seconds()}
+	if len(track.Artists) > 0 {
+		song.Artist = track.Artists[0].Name
 	}
-	return playlist, nil
+	return append(songs, song)
 }
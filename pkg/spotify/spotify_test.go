@@ -0,0 +1,132 @@
+package spotifyClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+	"golang.org/x/oauth2"
+)
+
+const pageSize = 2
+
+// newStubAPI returns a stub of the Spotify Web API serving a playlist and an
+// album with the given number of tracks, split into pages of pageSize tracks
+func newStubAPI(t *testing.T, tracks int, requests *int) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	page := func(path string, offset int, playlist bool) map[string]interface{} {
+		items := []interface{}{}
+		for i := offset; i < offset+pageSize && i < tracks; i++ {
+			track := map[string]interface{}{
+				"name":        fmt.Sprintf("track %d", i+1),
+				"duration_ms": 60000,
+				"artists":     []interface{}{map[string]interface{}{"name": "artist"}},
+			}
+			if playlist {
+				items = append(items, map[string]interface{}{"track": track})
+			} else {
+				items = append(items, track)
+			}
+		}
+		next := ""
+		if offset+pageSize < tracks {
+			next = fmt.Sprintf("%s%s?offset=%d&limit=%d", server.URL, path, offset+pageSize, pageSize)
+		}
+		return map[string]interface{}{"items": items, "next": next, "offset": offset, "limit": pageSize, "total": tracks}
+	}
+
+	mux := http.NewServeMux()
+	respond := func(w http.ResponseWriter, body interface{}) {
+		*requests++
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			t.Errorf("could not encode response, err=%v", err)
+		}
+	}
+	offset := func(r *http.Request) int {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		return offset
+	}
+	mux.HandleFunc("/playlists/list", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, map[string]interface{}{
+			"name":   "playlist",
+			"owner":  map[string]interface{}{"display_name": "owner"},
+			"tracks": page("/playlists/list/tracks", 0, true),
+		})
+	})
+	mux.HandleFunc("/playlists/list/tracks", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, page("/playlists/list/tracks", offset(r), true))
+	})
+	mux.HandleFunc("/albums/album", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, map[string]interface{}{
+			"name":   "album",
+			"tracks": page("/albums/album/tracks", 0, false),
+		})
+	})
+	mux.HandleFunc("/albums/album/tracks", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, page("/albums/album/tracks", offset(r), false))
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestClient(server *httptest.Server, maxTracks int) *Client {
+	return &Client{
+		client:    spotify.New(server.Client(), spotify.WithBaseURL(server.URL+"/")),
+		token:     &oauth2.Token{Expiry: time.Now().Add(time.Hour)},
+		maxTracks: maxTracks,
+	}
+}
+
+func TestClient_Pagination(t *testing.T) {
+	tests := []struct {
+		name         string
+		tracks       int
+		maxTracks    int
+		wantSongs    int
+		wantRequests int
+	}{
+		{name: "single page", tracks: 2, maxTracks: DefaultMaxTracks, wantSongs: 2, wantRequests: 1},
+		{name: "all pages", tracks: 5, maxTracks: DefaultMaxTracks, wantSongs: 5, wantRequests: 3},
+		{name: "capped", tracks: 9, maxTracks: 3, wantSongs: 3, wantRequests: 2},
+		{name: "empty", tracks: 0, maxTracks: DefaultMaxTracks, wantSongs: 0, wantRequests: 1},
+	}
+	for _, tt := range tests {
+		for _, kind := range []string{"playlist", "album"} {
+			t.Run(tt.name+" "+kind, func(t *testing.T) {
+				requests := 0
+				client := newTestClient(newStubAPI(t, tt.tracks, &requests), tt.maxTracks)
+
+				var playlist *Playlist
+				var err error
+				if kind == "playlist" {
+					playlist, err = client.GetPlaylist("list")
+				} else {
+					playlist, err = client.GetAlbum("album")
+				}
+				if err != nil {
+					t.Fatalf("Get%s() error = %v", kind, err)
+				}
+				if len(playlist.Songs) != tt.wantSongs {
+					t.Errorf("got %d songs, want %d", len(playlist.Songs), tt.wantSongs)
+				}
+				for i, song := range playlist.Songs {
+					want := Song{Artist: "artist", Name: fmt.Sprintf("track %d", i+1), Duration: 60}
+					if song != want {
+						t.Errorf("song %d = %+v, want %+v", i, song, want)
+					}
+				}
+				if requests != tt.wantRequests {
+					t.Errorf("made %d requests, want %d", requests, tt.wantRequests)
+				}
+			})
+		}
+	}
+}
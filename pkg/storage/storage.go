@@ -0,0 +1,179 @@
+// Package storage provides persistence of guild state for Surbot.
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+
+	"gitlab.com/sajfer/surbot/pkg/music"
+)
+
+// ErrNotFound is returned when no state is stored for a guild
+var ErrNotFound = errors.New("storage: guild not found")
+
+// Guild contains the persisted state of a guild
+type Guild struct {
+	ID     string `json:"id"`
+	Volume int    `json:"volume"`
+	// VolumeSet is set once the volume has been chosen with the volume
+	// command, the default volume of the settings is not used after that
+	VolumeSet bool `json:"volumeSet,omitempty"`
+	// TextChannelID is the channel the bot last posted music updates in
+	TextChannelID string `json:"textChannelId,omitempty"`
+	// VoiceChannelID is the channel the bot was connected to, if any
+	VoiceChannelID string         `json:"voiceChannelId,omitempty"`
+	CurrentSong    *music.Song    `json:"currentSong,omitempty"`
+	Position       float64        `json:"position,omitempty"`
+	Queue          []*music.Song  `json:"queue,omitempty"`
+	Loop           music.LoopMode `json:"loop,omitempty"`
+	Settings       Settings       `json:"settings"`
+}
+
+// Settings contains the configuration of a guild, zero values mean the
+// default of the bot is used
+type Settings struct {
+	Prefix   string `json:"prefix,omitempty"`
+	DJRoleID string `json:"djRoleId,omitempty"`
+	// DefaultVolume is the volume, in percent, used when the bot joins a voice channel
+	DefaultVolume int `json:"defaultVolume,omitempty"`
+	// IdleTimeout is how many minutes the bot stays in an idle voice channel
+	IdleTimeout int `json:"idleTimeout,omitempty"`
+	// NowPlayingChannelID is the channel new songs are announced in
+	NowPlayingChannelID string `json:"nowPlayingChannelId,omitempty"`
+	MaxQueueLength      int    `json:"maxQueueLength,omitempty"`
+	// MaxUserSongs is how many songs a single user can have in the queue
+	MaxUserSongs int `json:"maxUserSongs,omitempty"`
+	// FairQueue interleaves the songs in the queue by requester
+	FairQueue bool `json:"fairQueue,omitempty"`
+	// SkipRatio is the percentage of listeners that must vote to skip a song
+	SkipRatio int `json:"skipRatio,omitempty"`
+}
+
+// Storage persists the state of guilds
+type Storage interface {
+	// Load returns the stored state of a guild, or ErrNotFound
+	Load(guildID string) (*Guild, error)
+	// Save stores the state of a guild
+	Save(guild *Guild) error
+	// List returns the stored state of all guilds
+	List() ([]*Guild, error)
+	// Delete removes the stored state of a guild
+	Delete(guildID string) error
+}
+
+// MemoryStorage keeps guild state in memory only
+type MemoryStorage struct {
+	mu     sync.RWMutex
+	guilds map[string]Guild
+}
+
+// NewMemoryStorage returns an empty in-memory storage
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{guilds: make(map[string]Guild)}
+}
+
+// Load ...
+func (m *MemoryStorage) Load(guildID string) (*Guild, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	guild, ok := m.guilds[guildID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &guild, nil
+}
+
+// Save ...
+func (m *MemoryStorage) Save(guild *Guild) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.guilds[guild.ID] = *guild
+	return nil
+}
+
+// List ...
+func (m *MemoryStorage) List() ([]*Guild, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	guilds := make([]*Guild, 0, len(m.guilds))
+	for id := range m.guilds {
+		guild := m.guilds[id]
+		guilds = append(guilds, &guild)
+	}
+	sort.Slice(guilds, func(i, j int) bool { return guilds[i].ID < guilds[j].ID })
+	return guilds, nil
+}
+
+// Delete ...
+func (m *MemoryStorage) Delete(guildID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.guilds, guildID)
+	return nil
+}
+
+// FileStorage keeps guild state in memory and writes it to a JSON file on every save
+type FileStorage struct {
+	MemoryStorage
+	path string
+	// writeMu serializes writes of the file, so that an older state never
+	// replaces a newer one
+	writeMu sync.Mutex
+}
+
+// NewFileStorage returns a storage backed by the JSON file at path, loading
+// any state already stored in it
+func NewFileStorage(path string) (*FileStorage, error) {
+	storage := &FileStorage{MemoryStorage: *NewMemoryStorage(), path: path}
+	data, err := os.ReadFile(path) // #nosec G304
+	if errors.Is(err, os.ErrNotExist) {
+		return storage, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &storage.guilds)
+	if err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
+// Save ...
+func (f *FileStorage) Save(guild *Guild) error {
+	err := f.MemoryStorage.Save(guild)
+	if err != nil {
+		return err
+	}
+	return f.flush()
+}
+
+// Delete ...
+func (f *FileStorage) Delete(guildID string) error {
+	err := f.MemoryStorage.Delete(guildID)
+	if err != nil {
+		return err
+	}
+	return f.flush()
+}
+
+// flush writes all guild state to the file, replacing it atomically
+func (f *FileStorage) flush() error {
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	f.mu.RLock()
+	data, err := json.MarshalIndent(f.guilds, "", "  ")
+	f.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0o600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
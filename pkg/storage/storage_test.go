@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"gitlab.com/sajfer/surbot/pkg/music"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	storage := NewMemoryStorage()
+	if _, err := storage.Load("1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load() error = %v, want %v", err, ErrNotFound)
+	}
+
+	guild := &Guild{ID: "1", Volume: 50}
+	if err := storage.Save(guild); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	guild.Volume = 100
+
+	got, err := storage.Load("1")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if want := (&Guild{ID: "1", Volume: 50}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() = %v, want %v", got, want)
+	}
+}
+
+func TestFileStorage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	storage, err := NewFileStorage(path)
+	if err != nil {
+		t.Fatalf("NewFileStorage() error = %v", err)
+	}
+	guilds := []*Guild{
+		{ID: "1", Volume: 50},
+		{
+			ID:             "2",
+			Volume:         150,
+			VolumeSet:      true,
+			TextChannelID:  "3",
+			VoiceChannelID: "4",
+			CurrentSong:    &music.Song{Title: "current", Duration: 120, ID: "a"},
+			Position:       42,
+			Queue:          []*music.Song{{Title: "next", Duration: 60, ID: "b"}},
+			Loop:           music.LoopQueue,
+			Settings: Settings{
+				Prefix:              "?",
+				DJRoleID:            "5",
+				DefaultVolume:       80,
+				IdleTimeout:         10,
+				NowPlayingChannelID: "6",
+				MaxQueueLength:      100,
+				MaxUserSongs:        20,
+				FairQueue:           true,
+				SkipRatio:           60,
+			},
+		},
+	}
+	for _, guild := range guilds {
+		if err := storage.Save(guild); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	reopened, err := NewFileStorage(path)
+	if err != nil {
+		t.Fatalf("NewFileStorage() error = %v", err)
+	}
+	for _, want := range guilds {
+		got, err := reopened.Load(want.ID)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Load() = %v, want %v", got, want)
+		}
+	}
+
+	listed, err := reopened.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if !reflect.DeepEqual(listed, guilds) {
+		t.Errorf("List() = %v, want %v", listed, guilds)
+	}
+
+	if err := reopened.Delete("1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	reopened, err = NewFileStorage(path)
+	if err != nil {
+		t.Fatalf("NewFileStorage() error = %v", err)
+	}
+	if _, err := reopened.Load("1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestFileStorage_ConcurrentSaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	storage, err := NewFileStorage(path)
+	if err != nil {
+		t.Fatalf("NewFileStorage() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if err := storage.Save(&Guild{ID: id}); err != nil {
+				t.Errorf("Save() error = %v", err)
+			}
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+
+	reopened, err := NewFileStorage(path)
+	if err != nil {
+		t.Fatalf("NewFileStorage() error = %v", err)
+	}
+	listed, err := reopened.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(listed) != 20 {
+		t.Errorf("List() returned %d guilds, want %d", len(listed), 20)
+	}
+}
@@ -0,0 +1,337 @@
+// Package surbot contains the main functionality for Surbot.
+package surbot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sajfer/discordgo"
+	"gitlab.com/sajfer/surbot/internal/utils"
+)
+
+var errInvalidArguments = errors.New("invalid arguments")
+
+// Command is a command that can be invoked by users of the bot
+type Command interface {
+	// Name returns the name used to invoke the command
+	Name() string
+	// Aliases returns alternative names for the command
+	Aliases() []string
+	// Usage returns a short description of the arguments of the command
+	Usage() string
+	// Description returns a summary of what the command does
+	Description() string
+	// Permission returns the level of access required to run the command
+	Permission() Permission
+	// ParseArgs parses the raw argument string of an invocation
+	ParseArgs(raw string) ([]string, error)
+	// Run executes the command with the parsed arguments
+	Run(ctx *Context, args []string) error
+}
+
+// SlashCommand is a Command that can also be invoked as a Discord application command
+type SlashCommand interface {
+	Command
+	// Options returns the typed options of the application command, in the
+	// order they are passed to ParseArgs
+	Options() []*discordgo.ApplicationCommandOption
+}
+
+// Autocompleter is a Command that can suggest values for its options
+type Autocompleter interface {
+	Command
+	// Autocomplete returns suggestions for the partially typed value of an option
+	Autocomplete(ctx *Context, option, value string) []*discordgo.ApplicationCommandOptionChoice
+}
+
+// ArgParser parses the raw argument string of a command invocation
+type ArgParser func(raw string) ([]string, error)
+
+// Handler executes a command with parsed arguments
+type Handler func(ctx *Context, args []string) error
+
+// AutocompleteHandler returns suggestions for the partially typed value of an option
+type AutocompleteHandler func(ctx *Context, option, value string) []*discordgo.ApplicationCommandOptionChoice
+
+// BasicCommand is a Command built from a handler function
+type BasicCommand struct {
+	name         string
+	aliases      []string
+	usage        string
+	description  string
+	permission   Permission
+	parser       ArgParser
+	handler      Handler
+	options      []*discordgo.ApplicationCommandOption
+	autocomplete AutocompleteHandler
+}
+
+// NewCommand returns a command without arguments
+func NewCommand(name, description string, handler Handler) *BasicCommand {
+	return &BasicCommand{name: name, description: description, parser: noArgs, handler: handler}
+}
+
+// SetAliases sets the alternative names of the command
+func (c *BasicCommand) SetAliases(aliases ...string) *BasicCommand {
+	c.aliases = aliases
+	return c
+}
+
+// SetArgs sets the usage string and the parser for the arguments of the command
+func (c *BasicCommand) SetArgs(usage string, parser ArgParser) *BasicCommand {
+	c.usage = usage
+	c.parser = parser
+	return c
+}
+
+// SetPermission sets the level of access required to run the command
+func (c *BasicCommand) SetPermission(permission Permission) *BasicCommand {
+	c.permission = permission
+	return c
+}
+
+// SetOptions sets the typed options used when invoked as an application command
+func (c *BasicCommand) SetOptions(options ...*discordgo.ApplicationCommandOption) *BasicCommand {
+	c.options = options
+	return c
+}
+
+// SetAutocomplete sets the handler suggesting values for autocompleted options
+func (c *BasicCommand) SetAutocomplete(autocomplete AutocompleteHandler) *BasicCommand {
+	c.autocomplete = autocomplete
+	return c
+}
+
+// Name ...
+func (c *BasicCommand) Name() string {
+	return c.name
+}
+
+// Aliases ...
+func (c *BasicCommand) Aliases() []string {
+	return c.aliases
+}
+
+// Usage ...
+func (c *BasicCommand) Usage() string {
+	return c.usage
+}
+
+// Description ...
+func (c *BasicCommand) Description() string {
+	return c.description
+}
+
+// Permission ...
+func (c *BasicCommand) Permission() Permission {
+	return c.permission
+}
+
+// ParseArgs ...
+func (c *BasicCommand) ParseArgs(raw string) ([]string, error) {
+	return c.parser(raw)
+}
+
+// Run ...
+func (c *BasicCommand) Run(ctx *Context, args []string) error {
+	return c.handler(ctx, args)
+}
+
+// Options ...
+func (c *BasicCommand) Options() []*discordgo.ApplicationCommandOption {
+	return c.options
+}
+
+// Autocomplete ...
+func (c *BasicCommand) Autocomplete(ctx *Context, option, value string) []*discordgo.ApplicationCommandOptionChoice {
+	if c.autocomplete == nil {
+		return nil
+	}
+	return c.autocomplete(ctx, option, value)
+}
+
+// noArgs accepts invocations without arguments
+func noArgs(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) != "" {
+		return nil, errInvalidArguments
+	}
+	return nil, nil
+}
+
+// requiredArg passes the whole argument string as a single argument
+func requiredArg(raw string) ([]string, error) {
+	arg := strings.TrimSpace(raw)
+	if arg == "" {
+		return nil, errInvalidArguments
+	}
+	return []string{arg}, nil
+}
+
+// optionalArg passes the whole argument string as a single argument if there is one
+func optionalArg(raw string) ([]string, error) {
+	arg := strings.TrimSpace(raw)
+	if arg == "" {
+		return nil, nil
+	}
+	return []string{arg}, nil
+}
+
+// Registry keeps track of the commands known to the bot
+type Registry struct {
+	commands []Command
+	lookup   map[string]Command
+}
+
+// NewRegistry returns an empty command registry
+func NewRegistry() *Registry {
+	return &Registry{lookup: make(map[string]Command)}
+}
+
+// Register adds commands to the registry, names and aliases must be unique
+func (r *Registry) Register(commands ...Command) error {
+	for _, command := range commands {
+		names := append([]string{command.Name()}, command.Aliases()...)
+		for _, name := range names {
+			if _, ok := r.lookup[strings.ToLower(name)]; ok {
+				return fmt.Errorf("command %s is already registered", name)
+			}
+		}
+		for _, name := range names {
+			r.lookup[strings.ToLower(name)] = command
+		}
+		r.commands = append(r.commands, command)
+	}
+	return nil
+}
+
+// Lookup returns the command with the given name or alias
+func (r *Registry) Lookup(name string) (Command, bool) {
+	command, ok := r.lookup[strings.ToLower(name)]
+	return command, ok
+}
+
+// Commands returns all registered commands in registration order
+func (r *Registry) Commands() []Command {
+	return r.commands
+}
+
+// Help returns the help entries of all registered commands
+func (r *Registry) Help() []utils.CommandHelp {
+	help := make([]utils.CommandHelp, 0, len(r.commands))
+	for _, command := range r.commands {
+		help = append(help, utils.CommandHelp{
+			Name:        command.Name(),
+			Usage:       command.Usage(),
+			Description: command.Description(),
+		})
+	}
+	return help
+}
+
+// ApplicationCommands returns the application commands of all registered
+// commands that support being invoked as slash commands
+func (r *Registry) ApplicationCommands() []*discordgo.ApplicationCommand {
+	applicationCommands := make([]*discordgo.ApplicationCommand, 0, len(r.commands))
+	for _, command := range r.commands {
+		slash, ok := command.(SlashCommand)
+		if !ok {
+			continue
+		}
+		applicationCommands = append(applicationCommands, &discordgo.ApplicationCommand{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        strings.ToLower(command.Name()),
+			Description: command.Description(),
+			Options:     slash.Options(),
+		})
+	}
+	return applicationCommands
+}
+
+// splitCommand splits a message into the command name and the raw arguments
+func splitCommand(message string) (string, string) {
+	message = strings.TrimSpace(message)
+	index := strings.IndexAny(message, " \t\n")
+	if index == -1 {
+		return message, ""
+	}
+	return message[:index], strings.TrimSpace(message[index+1:])
+}
+
+// Context contains information about a command invocation
+type Context struct {
+	Session     *discordgo.Session
+	Server      *Server
+	GuildID     string
+	ChannelID   string
+	Author      *discordgo.User
+	Member      *discordgo.Member
+	Prefix      string
+	Interaction *discordgo.Interaction
+	responded   bool
+}
+
+// Voice returns the voice of the server, bound to the invoking text channel
+func (ctx *Context) Voice() *Voice {
+	voice := ctx.Server.voice
+	voice.SetTextChannel(ctx.ChannelID)
+	voice.SetSession(ctx.Session)
+	return voice
+}
+
+// authorName returns the name the author is shown with in the guild
+func (ctx *Context) authorName() string {
+	if ctx.Member != nil && ctx.Member.Nick != "" {
+		return ctx.Member.Nick
+	}
+	if ctx.Author.GlobalName != "" {
+		return ctx.Author.GlobalName
+	}
+	return ctx.Author.Username
+}
+
+// Send replies to the invocation with a message
+func (ctx *Context) Send(content string) error {
+	if ctx.Interaction != nil {
+		_, err := ctx.respond(&discordgo.WebhookParams{Content: content})
+		return err
+	}
+	_, err := ctx.Session.ChannelMessageSend(ctx.ChannelID, content)
+	return err
+}
+
+// SendEmbed replies to the invocation with an embed
+func (ctx *Context) SendEmbed(embed *discordgo.MessageEmbed) error {
+	if ctx.Interaction != nil {
+		_, err := ctx.respond(&discordgo.WebhookParams{Embeds: []*discordgo.MessageEmbed{embed}})
+		return err
+	}
+	_, err := ctx.Session.ChannelMessageSendEmbed(ctx.ChannelID, embed)
+	return err
+}
+
+// SendComplex replies to the invocation with a message that can have
+// components, and returns the message
+func (ctx *Context) SendComplex(data *discordgo.MessageSend) (*discordgo.Message, error) {
+	if ctx.Interaction != nil {
+		return ctx.respond(&discordgo.WebhookParams{Content: data.Content, Embeds: data.Embeds, Components: data.Components})
+	}
+	return ctx.Session.ChannelMessageSendComplex(ctx.ChannelID, data)
+}
+
+// respond replaces the deferred response of the interaction the first time it
+// is called, later replies are sent as followup messages
+func (ctx *Context) respond(params *discordgo.WebhookParams) (*discordgo.Message, error) {
+	if ctx.responded {
+		return ctx.Session.FollowupMessageCreate(ctx.Interaction, true, params)
+	}
+	ctx.responded = true
+	edit := &discordgo.WebhookEdit{
+		Content: &params.Content,
+		Embeds:  &params.Embeds,
+	}
+	if len(params.Components) > 0 {
+		edit.Components = &params.Components
+	}
+	return ctx.Session.InteractionResponseEdit(ctx.Interaction, edit)
+}
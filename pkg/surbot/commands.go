@@ -0,0 +1,675 @@
+// Package surbot contains the main functionality for Surbot.
+package surbot
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sajfer/discordgo"
+	"gitlab.com/sajfer/surbot/internal/logger"
+	"gitlab.com/sajfer/surbot/internal/utils"
+	"gitlab.com/sajfer/surbot/pkg/music"
+	"gitlab.com/sajfer/surbot/pkg/youtube"
+)
+
+const (
+	rajdChannel        = "1006248135737221251"
+	minDiceSide        = 2
+	maxDiceSide        = 1000
+	autocompleteLength = 3
+	autocompleteResult = 5
+	choiceNameLength   = 100
+	libraryResults     = 10
+	// autocompleteTimeout leaves time to respond within the three seconds
+	// discord waits for autocomplete choices
+	autocompleteTimeout = 2 * time.Second
+)
+
+var (
+	minDiceSideOption float64 = minDiceSide
+	minVolumeOption   float64
+	minPositionOption float64 = 1
+)
+
+// registerCommands adds the builtin commands to the registry of the bot
+func (surbot *Surbot) registerCommands() error {
+	return surbot.commands.Register(
+		NewCommand("help", "Show this command", surbot.help),
+		NewCommand("ping", "Respods with pong!", ping),
+		NewCommand("chuck", "Responds with chuck norris joke", chuck),
+		NewCommand("play", "Play a link or a file from the library, or search youtube, without one the queue continues", surbot.play).
+			SetArgs("[link|query]", optionalArg).
+			SetOptions(&discordgo.ApplicationCommandOption{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "query",
+				Description:  "Youtube, spotify, soundcloud, bandcamp or audio link, file:<name>, or a search query",
+				Autocomplete: true,
+			}).
+			SetAutocomplete(surbot.playAutocomplete),
+		NewCommand("playnext", "Add a song to the front of the queue", surbot.playNext).
+			SetPermission(PermissionDJ).
+			SetArgs("<link|query>", requiredArg).
+			SetOptions(&discordgo.ApplicationCommandOption{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "query",
+				Description:  "Youtube, spotify, soundcloud, bandcamp or audio link, file:<name>, or a search query",
+				Required:     true,
+				Autocomplete: true,
+			}).
+			SetAutocomplete(surbot.playAutocomplete),
+		NewCommand("search", "Search youtube and pick which result to play", surbot.search).
+			SetArgs("<query>", requiredArg).
+			SetOptions(&discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "query",
+				Description: "What to search youtube for",
+				Required:    true,
+			}),
+		NewCommand("library", "Search the local library, play its files with play file:<name>", surbot.library).
+			SetArgs("[query]", optionalArg).
+			SetOptions(&discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "query",
+				Description: "Part of a file name, title or artist",
+			}),
+		NewCommand("playing", "Show the song that is currently playing", playing).
+			SetAliases("np"),
+		NewCommand("pause", "Pause the current song", pause).
+			SetPermission(PermissionDJ),
+		NewCommand("resume", "Resume the paused song", resume).
+			SetPermission(PermissionDJ),
+		NewCommand("volume", "Show or set the volume in percent", surbot.volume).
+			SetPermission(PermissionDJ).
+			SetArgs("[0-200]", volumeArgs).
+			SetOptions(&discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "percent",
+				Description: "Volume in percent",
+				MinValue:    &minVolumeOption,
+				MaxValue:    maxVolume,
+			}),
+		NewCommand("seek", "Jump to a position in the current song", seek).
+			SetPermission(PermissionDJ).
+			SetArgs("<mm:ss|+30s|-10s>", seekArgs).
+			SetOptions(&discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "position",
+				Description: "Timestamp such as 1:30, or an offset such as +30s or -10s",
+				Required:    true,
+			}),
+		NewCommand("loop", "Repeat the current song or the whole queue", loop).
+			SetPermission(PermissionDJ).
+			SetArgs("[off|track|queue]", loopArgs).
+			SetOptions(&discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "mode",
+				Description: "Loop mode, cycles through the modes when left out",
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: music.LoopOff.String(), Value: music.LoopOff.String()},
+					{Name: music.LoopTrack.String(), Value: music.LoopTrack.String()},
+					{Name: music.LoopQueue.String(), Value: music.LoopQueue.String()},
+				},
+			}),
+		NewCommand("stop", "Stop playing music", stop).
+			SetPermission(PermissionDJ),
+		NewCommand("skip", "Skip the current song", skip),
+		NewCommand("voteskip", "Vote to skip the current song", surbot.voteSkip).
+			SetAliases("vs"),
+		NewCommand("queue", "Show the queue of music", queue),
+		NewCommand("shuffle", "Shuffle the songs in the queue", shuffle).
+			SetPermission(PermissionDJ),
+		NewCommand("remove", "Remove a song or a range of songs from the queue", remove).
+			SetPermission(PermissionDJ).
+			SetArgs("<n|a-b>", rangeArgs).
+			SetOptions(&discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "position",
+				Description: "Position in the queue, or a range such as 2-5",
+				Required:    true,
+			}),
+		NewCommand("move", "Move a song to another position in the queue", move).
+			SetPermission(PermissionDJ).
+			SetArgs("<from> <to>", positionArgs(2)).
+			SetOptions(positionOption("from", "Position of the song to move"), positionOption("to", "New position of the song")),
+		NewCommand("skipto", "Skip to a position in the queue", skipTo).
+			SetPermission(PermissionDJ).
+			SetArgs("<n>", positionArgs(1)).
+			SetOptions(positionOption("position", "Position in the queue")),
+		NewCommand("removemine", "Remove the songs you added from the queue", removeMine),
+		NewCommand("removedupes", "Remove duplicate songs from the queue", removeDupes).
+			SetPermission(PermissionDJ),
+		NewCommand("clearQueue", "Remove all songs from the queue", clearQueue).
+			SetPermission(PermissionDJ),
+		NewCommand("disconnect", "Leave the voice channel", disconnect).
+			SetPermission(PermissionDJ),
+		NewCommand("roll", "Roll a dice", roll).
+			SetArgs("d<sides>", diceArgs).
+			SetOptions(&discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "sides",
+				Description: "Number of sides of the dice",
+				Required:    true,
+				MinValue:    &minDiceSideOption,
+				MaxValue:    maxDiceSide,
+			}),
+		NewCommand("rajd", "Post raid attendance for the coming days", rajd),
+		NewCommand("config", "Show or change the settings of the server", surbot.config).
+			SetPermission(PermissionAdmin).
+			SetArgs("[setting] [value|reset]", configArgs).
+			SetOptions(configOption(), &discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "value",
+				Description: "New value of the setting, or reset to restore the default",
+			}),
+	)
+}
+
+func (surbot *Surbot) help(ctx *Context, _ []string) error {
+	return ctx.Send(utils.HelpMessage(surbot.commands.Help()))
+}
+
+func ping(ctx *Context, _ []string) error {
+	return ctx.Send("Pong!")
+}
+
+func chuck(ctx *Context, _ []string) error {
+	return ctx.Send(utils.GetChuckJoke())
+}
+
+func (surbot *Surbot) play(ctx *Context, args []string) error {
+	logger.Log.Debugln("Playing music")
+	voice := ctx.Voice()
+	if len(args) == 0 {
+		return continueQueue(ctx)
+	}
+
+	playlist, err := surbot.musicClients.FetchSong(args[0])
+	if err != nil {
+		return fmt.Errorf("could not fetch song information, err=%w", err)
+	}
+	err = fitQueue(ctx, playlist)
+	if err != nil {
+		return err
+	}
+	setRequester(ctx, playlist)
+	err = voice.music.AddToQueue(*playlist)
+	if err != nil {
+		return fmt.Errorf("could not add songs to playlist, err=%w", err)
+	}
+
+	err = startPlaying(ctx)
+	if err != nil {
+		return fmt.Errorf("could not play song, err=%w", err)
+	}
+	return nil
+}
+
+// continueQueue plays the songs left in the queue, such as after playback
+// stopped on songs that could not be found
+func continueQueue(ctx *Context) error {
+	voice := ctx.Voice()
+	if voice.isActive() {
+		return ctx.Send("Already playing the queue")
+	}
+	if voice.music.Len() == 0 {
+		return ctx.SendEmbed(NewErrorEmbed("Nothing to play", "The queue is empty, use %splay <link|query> to queue a song", ctx.Prefix))
+	}
+	err := startPlaying(ctx)
+	if err != nil {
+		return fmt.Errorf("could not play song, err=%w", err)
+	}
+	return ctx.Send(fmt.Sprintf("Continuing the queue, %d songs left", voice.music.Len()))
+}
+
+// startPlaying joins the voice channel of the author and plays the queue,
+// the default volume of the server is used when joining a channel unless a
+// volume has been set with the volume command
+func startPlaying(ctx *Context) error {
+	voice := ctx.Voice()
+	if voice.VoiceChannel == nil && !ctx.Server.volumeSet && ctx.Server.settings.DefaultVolume > 0 {
+		ctx.Server.volume = ctx.Server.settings.DefaultVolume
+		err := voice.SetVolume(ctx.Server.volume)
+		if err != nil {
+			return err
+		}
+	}
+	return voice.Start(ctx.GuildID, ctx.Author.ID)
+}
+
+// fitQueue drops the songs of the playlist that do not fit in the queue of
+// the server or within the songs allowed per user, and tells the author about them
+func fitQueue(ctx *Context, playlist *music.Playlist) error {
+	settings := ctx.Server.settings
+	queue := ctx.Server.voice.music
+	free := len(playlist.Songs)
+	limit, reason := 0, ""
+	if settings.MaxQueueLength > 0 && settings.MaxQueueLength-queue.Len() < free {
+		free = settings.MaxQueueLength - queue.Len()
+		limit, reason = settings.MaxQueueLength, "The queue is limited to %d songs"
+	}
+	if settings.MaxUserSongs > 0 && settings.MaxUserSongs-queue.CountRequester(ctx.Author.ID) < free {
+		free = settings.MaxUserSongs - queue.CountRequester(ctx.Author.ID)
+		limit, reason = settings.MaxUserSongs, "You can have at most %d songs in the queue"
+	}
+	if free >= len(playlist.Songs) {
+		return nil
+	}
+	if free <= 0 {
+		playlist.Songs = nil
+		return ctx.SendEmbed(NewErrorEmbed("Queue is full", reason, limit))
+	}
+	playlist.Songs = playlist.Songs[:free]
+	return ctx.Send(fmt.Sprintf(reason+", only the first %d songs were added", limit, free))
+}
+
+// setRequester marks the author of the invocation as the requester of the songs
+func setRequester(ctx *Context, playlist *music.Playlist) {
+	for _, song := range playlist.Songs {
+		song.RequesterID = ctx.Author.ID
+		song.RequesterName = ctx.authorName()
+		song.RequesterAvatar = ctx.Author.AvatarURL("")
+		song.ChannelID = ctx.ChannelID
+	}
+}
+
+// playAutocomplete suggests youtube videos matching the partially typed query,
+// or files of the local library for queries starting with the file prefix
+func (surbot *Surbot) playAutocomplete(_ *Context, _, value string) []*discordgo.ApplicationCommandOptionChoice {
+	if strings.HasPrefix(strings.ToLower(value), music.LocalPrefix) {
+		return surbot.libraryAutocomplete(value[len(music.LocalPrefix):])
+	}
+	if len(value) < autocompleteLength || utils.IsYoutubeUrl(value) || utils.IsSpotifyUrl(value) ||
+		utils.IsSoundcloudUrl(value) || utils.IsBandcampUrl(value) {
+		return nil
+	}
+	results := surbot.suggestVideos(value)
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(results))
+	for _, result := range results {
+		name := []rune(fmt.Sprintf("%s (%s)", result.Title, result.Duration))
+		if len(name) > choiceNameLength {
+			name = name[:choiceNameLength]
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: string(name), Value: result.Path})
+	}
+	return choices
+}
+
+// suggestVideos searches youtube for autocompletion, it gives up when the
+// search takes too long to respond in time, the results are still cached for
+// the next keystroke
+func (surbot *Surbot) suggestVideos(query string) []*youtube.SearchResult {
+	found := make(chan []*youtube.SearchResult, 1)
+	go func() {
+		found <- surbot.musicClients.Youtube.SuggestVideos(query, autocompleteResult)
+	}()
+	select {
+	case results := <-found:
+		return results
+	case <-time.After(autocompleteTimeout):
+		return nil
+	}
+}
+
+// libraryAutocomplete suggests files of the local library matching the query
+func (surbot *Surbot) libraryAutocomplete(query string) []*discordgo.ApplicationCommandOptionChoice {
+	library := surbot.musicClients.Library
+	if library == nil {
+		return nil
+	}
+	tracks := library.Search(query, autocompleteResult)
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(tracks))
+	for _, track := range tracks {
+		name := []rune(fmt.Sprintf("%s (%s)", track.Name, utils.SecondsToHuman(track.Duration)))
+		if len(name) > choiceNameLength {
+			name = name[:choiceNameLength]
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: string(name), Value: music.LocalPrefix + track.Name})
+	}
+	return choices
+}
+
+// library lists the files of the local library matching the query
+func (surbot *Surbot) library(ctx *Context, args []string) error {
+	library := surbot.musicClients.Library
+	if library == nil {
+		return ctx.SendEmbed(NewErrorEmbed("No library", "No local library is configured"))
+	}
+	query := ""
+	if len(args) > 0 {
+		query = args[0]
+	}
+	tracks := library.Search(query, libraryResults)
+	if len(tracks) == 0 && query == "" {
+		return ctx.SendEmbed(NewErrorEmbed("No files found", "The library is empty"))
+	}
+	if len(tracks) == 0 {
+		return ctx.SendEmbed(NewErrorEmbed("No files found", "No files in the library match %s", query))
+	}
+	var list strings.Builder
+	for _, track := range tracks {
+		artist := ""
+		if track.Artist != "" {
+			artist = " by " + track.Artist
+		}
+		list.WriteString(fmt.Sprintf("`%s%s` %s%s `[%s]`\n", music.LocalPrefix, track.Name, shortTitle(track.Title), artist, utils.SecondsToHuman(track.Duration)))
+	}
+	embed := NewEmbed().
+		SetTitle("Library").
+		SetDescription(list.String()).
+		SetFooter(fmt.Sprintf("%d files in the library", library.Len()))
+	return ctx.SendEmbed(embed.MessageEmbed)
+}
+
+func (surbot *Surbot) playNext(ctx *Context, args []string) error {
+	voice := ctx.Voice()
+
+	playlist, err := surbot.musicClients.FetchSong(args[0])
+	if err != nil {
+		return fmt.Errorf("could not fetch song information, err=%w", err)
+	}
+	err = fitQueue(ctx, playlist)
+	if err != nil {
+		return err
+	}
+	setRequester(ctx, playlist)
+	voice.music.PlayNext(*playlist)
+
+	err = startPlaying(ctx)
+	if err != nil {
+		return fmt.Errorf("could not play song, err=%w", err)
+	}
+	return nil
+}
+
+func playing(ctx *Context, _ []string) error {
+	return ctx.SendEmbed(ctx.Voice().nowPlayingEmbed())
+}
+
+func pause(ctx *Context, _ []string) error {
+	return ctx.Voice().Pause()
+}
+
+func resume(ctx *Context, _ []string) error {
+	return ctx.Voice().Resume()
+}
+
+// volumeArgs accepts an optional volume in percent
+func volumeArgs(raw string) ([]string, error) {
+	raw = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(raw), "%"))
+	if raw == "" {
+		return nil, nil
+	}
+	volume, err := strconv.Atoi(raw)
+	if err != nil || volume < 0 || volume > maxVolume {
+		return nil, errInvalidArguments
+	}
+	return []string{raw}, nil
+}
+
+func (surbot *Surbot) volume(ctx *Context, args []string) error {
+	if len(args) == 0 {
+		return ctx.Send(fmt.Sprintf("Volume is %d%%", ctx.Server.volume))
+	}
+	volume, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+	ctx.Server.volume = volume
+	ctx.Server.volumeSet = true
+	err = ctx.Voice().SetVolume(volume)
+	if err != nil {
+		return fmt.Errorf("could not change volume, err=%w", err)
+	}
+	err = surbot.saveServer(ctx.Server)
+	if err != nil {
+		logger.Log.Warningf("could not save volume, err=%v", err)
+	}
+	return ctx.Send(fmt.Sprintf("Volume set to %d%%", volume))
+}
+
+// seekArgs accepts an absolute timestamp or a relative offset prefixed with + or -
+func seekArgs(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	var err error
+	if strings.HasPrefix(raw, "+") || strings.HasPrefix(raw, "-") {
+		_, err = time.ParseDuration(raw)
+	} else {
+		_, err = utils.ParseTimestamp(raw)
+	}
+	if err != nil {
+		return nil, errInvalidArguments
+	}
+	return []string{raw}, nil
+}
+
+func seek(ctx *Context, args []string) error {
+	voice := ctx.Voice()
+	var offset time.Duration
+	var err error
+	if strings.HasPrefix(args[0], "+") || strings.HasPrefix(args[0], "-") {
+		offset, err = time.ParseDuration(args[0])
+		offset += voice.Position()
+	} else {
+		offset, err = utils.ParseTimestamp(args[0])
+	}
+	if err != nil {
+		return err
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	err = voice.Seek(offset)
+	if errors.Is(err, errVoiceSeekOutOfRange) {
+		return ctx.SendEmbed(NewErrorEmbed("Could not seek", "%s is beyond the end of the song", utils.SecondsToHuman(offset.Seconds())))
+	}
+	if errors.Is(err, errVoiceSeekLive) {
+		return ctx.SendEmbed(NewErrorEmbed("Could not seek", "Live streams cannot be seeked"))
+	}
+	if err != nil {
+		return fmt.Errorf("could not seek, err=%w", err)
+	}
+	return ctx.Send(fmt.Sprintf("Jumped to %s", utils.SecondsToHuman(offset.Seconds())))
+}
+
+// loopArgs accepts an optional loop mode
+func loopArgs(raw string) ([]string, error) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if raw == "" {
+		return nil, nil
+	}
+	if _, err := music.ParseLoopMode(raw); err != nil {
+		return nil, errInvalidArguments
+	}
+	return []string{raw}, nil
+}
+
+func loop(ctx *Context, args []string) error {
+	musicQueue := ctx.Voice().music
+	mode := (musicQueue.Loop() + 1) % (music.LoopQueue + 1)
+	if len(args) > 0 {
+		var err error
+		mode, err = music.ParseLoopMode(args[0])
+		if err != nil {
+			return err
+		}
+	}
+	musicQueue.SetLoop(mode)
+	return ctx.Send(fmt.Sprintf("Loop: %s", mode))
+}
+
+func stop(ctx *Context, _ []string) error {
+	return ctx.Voice().Stop()
+}
+
+func removeMine(ctx *Context, _ []string) error {
+	removed := ctx.Voice().music.RemoveRequester(ctx.Author.ID)
+	return ctx.Send(fmt.Sprintf("Removed %d of your songs from the queue", removed))
+}
+
+func queue(ctx *Context, _ []string) error {
+	return showQueue(ctx)
+}
+
+// showQueue replies with the first page of the queue
+func showQueue(ctx *Context) error {
+	embed, buttons := ctx.Voice().queueEmbed(0)
+	_, err := ctx.SendComplex(&discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: buttons,
+	})
+	return err
+}
+
+func shuffle(ctx *Context, _ []string) error {
+	ctx.Voice().music.Shuffle()
+	return nil
+}
+
+// positionOption returns an application command option for a queue position
+func positionOption(name, description string) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionInteger,
+		Name:        name,
+		Description: description,
+		Required:    true,
+		MinValue:    &minPositionOption,
+	}
+}
+
+// positionArgs returns a parser accepting count queue positions
+func positionArgs(count int) ArgParser {
+	return func(raw string) ([]string, error) {
+		args := strings.Fields(raw)
+		if len(args) != count {
+			return nil, errInvalidArguments
+		}
+		for _, arg := range args {
+			if position, err := strconv.Atoi(arg); err != nil || position < 1 {
+				return nil, errInvalidArguments
+			}
+		}
+		return args, nil
+	}
+}
+
+// rangeArgs accepts a queue position or a range of positions such as 2-5
+func rangeArgs(raw string) ([]string, error) {
+	from, to, found := strings.Cut(strings.TrimSpace(raw), "-")
+	if !found {
+		to = from
+	}
+	return positionArgs(2)(strings.TrimSpace(from) + " " + strings.TrimSpace(to))
+}
+
+func remove(ctx *Context, args []string) error {
+	from, _ := strconv.Atoi(args[0])
+	to, _ := strconv.Atoi(args[1])
+	removed, err := ctx.Voice().music.Remove(from, to)
+	if errors.Is(err, music.ErrInvalidPosition) {
+		return ctx.SendEmbed(NewErrorEmbed("Could not remove", "There is no song at that position in the queue"))
+	}
+	if err != nil {
+		return err
+	}
+	if len(removed) == 1 {
+		return ctx.Send(fmt.Sprintf("Removed %s", removed[0].Title))
+	}
+	return ctx.Send(fmt.Sprintf("Removed %d songs", len(removed)))
+}
+
+func move(ctx *Context, args []string) error {
+	from, _ := strconv.Atoi(args[0])
+	to, _ := strconv.Atoi(args[1])
+	err := ctx.Voice().music.Move(from, to)
+	if errors.Is(err, music.ErrInvalidPosition) {
+		return ctx.SendEmbed(NewErrorEmbed("Could not move", "There is no song at that position in the queue"))
+	}
+	if err != nil {
+		return err
+	}
+	return showQueue(ctx)
+}
+
+func skipTo(ctx *Context, args []string) error {
+	position, _ := strconv.Atoi(args[0])
+	voice := ctx.Voice()
+	err := voice.music.SkipTo(position)
+	if errors.Is(err, music.ErrInvalidPosition) {
+		return ctx.SendEmbed(NewErrorEmbed("Could not skip", "There is no song at that position in the queue"))
+	}
+	if err != nil {
+		return err
+	}
+	if !voice.isPlaying() {
+		return nil
+	}
+	return voice.Skip()
+}
+
+func removeDupes(ctx *Context, _ []string) error {
+	removed := ctx.Voice().music.RemoveDupes()
+	return ctx.Send(fmt.Sprintf("Removed %d duplicate songs", removed))
+}
+
+func clearQueue(ctx *Context, _ []string) error {
+	ctx.Voice().music.ClearQueue()
+	embed := NewEmbed()
+	embed.SetTitle("Queue")
+	embed.AddField("Queue have been cleared", fmt.Sprintf("Use %splay <youtube link|spotify link> to queue a song", ctx.Prefix))
+	return ctx.SendEmbed(embed.MessageEmbed)
+}
+
+func disconnect(ctx *Context, _ []string) error {
+	return ctx.Voice().Disconnect()
+}
+
+// diceArgs parses the number of sides from dice notation such as d20
+func diceArgs(raw string) ([]string, error) {
+	dice := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(raw)), "d")
+	sides, err := strconv.Atoi(dice)
+	if err != nil || sides < minDiceSide || sides > maxDiceSide {
+		return nil, errInvalidArguments
+	}
+	return []string{dice}, nil
+}
+
+func roll(ctx *Context, args []string) error {
+	sides, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	roll, err := rand.Int(rand.Reader, big.NewInt(sides))
+	if err != nil {
+		return fmt.Errorf("could not generate random number, err=%w", err)
+	}
+	return ctx.Send(roll.Add(roll, big.NewInt(1)).String())
+}
+
+func rajd(ctx *Context, _ []string) error {
+	currentTime := time.Now()
+	for {
+		msg, err := ctx.Session.ChannelMessageSend(rajdChannel, currentTime.Format("Monday 01/02"))
+		if err != nil {
+			return err
+		}
+		err = ctx.Session.MessageReactionAdd(rajdChannel, msg.ID, "✅")
+		if err != nil {
+			logger.Log.Warning("could not add emote,", err)
+		}
+		err = ctx.Session.MessageReactionAdd(rajdChannel, msg.ID, "❌")
+		if err != nil {
+			logger.Log.Warning("could not add emote,", err)
+		}
+		currentTime = currentTime.AddDate(0, 0, 1)
+		if currentTime.Format("Monday") == "Wednesday" {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+// Package surbot contains the main functionality for Surbot.
+package surbot
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sajfer/discordgo"
+	"gitlab.com/sajfer/surbot/internal/logger"
+	"gitlab.com/sajfer/surbot/pkg/storage"
+)
+
+const (
+	// resetValue restores the default of a setting
+	resetValue      = "reset"
+	maxPrefixLength = 5
+	maxIdleTimeout  = 24 * 60
+	maxQueueLength  = 10000
+	// defaultSkipRatio is the percentage of listeners that must vote to skip a song
+	defaultSkipRatio = 50
+)
+
+var snowflake = regexp.MustCompile(`^[0-9]+$`)
+
+// setting is a guild setting that can be changed with the config command
+type setting struct {
+	name        string
+	description string
+	get         func(settings *storage.Settings) string
+	set         func(settings *storage.Settings, value string) error
+}
+
+// guildSettings are the settings that can be changed with the config command
+var guildSettings = []setting{
+	{
+		name:        "prefix",
+		description: "Prefix of text commands",
+		get: func(settings *storage.Settings) string {
+			return orDefault(settings.Prefix, settings.Prefix)
+		},
+		set: func(settings *storage.Settings, value string) error {
+			if len(value) > maxPrefixLength || strings.ContainsAny(value, " \t\n") {
+				return fmt.Errorf("the prefix must be at most %d characters without spaces", maxPrefixLength)
+			}
+			settings.Prefix = value
+			return nil
+		},
+	},
+	{
+		name:        "djrole",
+		description: "Role allowed to control the music",
+		get: func(settings *storage.Settings) string {
+			return orDefault(settings.DJRoleID, fmt.Sprintf("<@&%s>", settings.DJRoleID))
+		},
+		set: func(settings *storage.Settings, value string) error {
+			id, err := parseMention(value, "<@&")
+			settings.DJRoleID = id
+			return err
+		},
+	},
+	{
+		name:        "volume",
+		description: "Volume in percent used when joining a voice channel",
+		get: func(settings *storage.Settings) string {
+			return orDefault(settings.DefaultVolume, fmt.Sprintf("%d%%", settings.DefaultVolume))
+		},
+		set: func(settings *storage.Settings, value string) error {
+			volume, err := parseInt(strings.TrimSuffix(value, "%"), 1, maxVolume)
+			settings.DefaultVolume = volume
+			return err
+		},
+	},
+	{
+		name:        "timeout",
+		description: "Minutes before leaving an idle voice channel",
+		get: func(settings *storage.Settings) string {
+			return orDefault(settings.IdleTimeout, fmt.Sprintf("%d minutes", settings.IdleTimeout))
+		},
+		set: func(settings *storage.Settings, value string) error {
+			minutes, err := parseInt(value, 1, maxIdleTimeout)
+			settings.IdleTimeout = minutes
+			return err
+		},
+	},
+	{
+		name:        "nowplaying",
+		description: "Channel new songs are announced in",
+		get: func(settings *storage.Settings) string {
+			return orDefault(settings.NowPlayingChannelID, fmt.Sprintf("<#%s>", settings.NowPlayingChannelID))
+		},
+		set: func(settings *storage.Settings, value string) error {
+			id, err := parseMention(value, "<#")
+			settings.NowPlayingChannelID = id
+			return err
+		},
+	},
+	{
+		name:        "maxqueue",
+		description: "Maximum number of songs in the queue",
+		get: func(settings *storage.Settings) string {
+			return orDefault(settings.MaxQueueLength, strconv.Itoa(settings.MaxQueueLength))
+		},
+		set: func(settings *storage.Settings, value string) error {
+			length, err := parseInt(value, 1, maxQueueLength)
+			settings.MaxQueueLength = length
+			return err
+		},
+	},
+	{
+		name:        "maxusersongs",
+		description: "Maximum number of songs per user in the queue",
+		get: func(settings *storage.Settings) string {
+			return orDefault(settings.MaxUserSongs, strconv.Itoa(settings.MaxUserSongs))
+		},
+		set: func(settings *storage.Settings, value string) error {
+			length, err := parseInt(value, 1, maxQueueLength)
+			settings.MaxUserSongs = length
+			return err
+		},
+	},
+	{
+		name:        "fairqueue",
+		description: "Interleave the songs in the queue by requester, on or off",
+		get: func(settings *storage.Settings) string {
+			if settings.FairQueue {
+				return "on"
+			}
+			return "off"
+		},
+		set: func(settings *storage.Settings, value string) error {
+			fair, err := parseSwitch(value)
+			settings.FairQueue = fair
+			return err
+		},
+	},
+	{
+		name:        "skipratio",
+		description: "Percentage of listeners that must vote to skip a song",
+		get: func(settings *storage.Settings) string {
+			return orDefault(settings.SkipRatio, fmt.Sprintf("%d%%", settings.SkipRatio))
+		},
+		set: func(settings *storage.Settings, value string) error {
+			ratio, err := parseInt(strings.TrimSuffix(value, "%"), 1, 100)
+			settings.SkipRatio = ratio
+			return err
+		},
+	},
+}
+
+// orDefault returns value formatted, or "default" when the setting is not set
+func orDefault[T comparable](value T, formatted string) string {
+	var zero T
+	if value == zero {
+		return "default"
+	}
+	return formatted
+}
+
+// parseMention returns the ID of a mention with the given prefix, or a plain ID
+func parseMention(value, prefix string) (string, error) {
+	id := strings.TrimSuffix(strings.TrimPrefix(value, prefix), ">")
+	if !snowflake.MatchString(id) {
+		return "", errors.New("expected a mention or an ID")
+	}
+	return id, nil
+}
+
+// parseInt parses an integer between min and max
+func parseInt(value string, min, max int) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < min || number > max {
+		return 0, fmt.Errorf("expected a number between %d and %d", min, max)
+	}
+	return number, nil
+}
+
+// parseSwitch parses on or off
+func parseSwitch(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on", "true", "yes":
+		return true, nil
+	case "off", "false", "no":
+		return false, nil
+	}
+	return false, errors.New("expected on or off")
+}
+
+// lookupSetting returns the guild setting with the given name
+func lookupSetting(name string) (setting, bool) {
+	for _, setting := range guildSettings {
+		if setting.name == strings.ToLower(name) {
+			return setting, true
+		}
+	}
+	return setting{}, false
+}
+
+// configOption returns the application command option selecting a setting
+func configOption() *discordgo.ApplicationCommandOption {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(guildSettings))
+	for _, setting := range guildSettings {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: setting.name, Value: setting.name})
+	}
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "setting",
+		Description: "Setting to show or change",
+		Choices:     choices,
+	}
+}
+
+// configArgs accepts an optional setting name followed by an optional value
+func configArgs(raw string) ([]string, error) {
+	name, value := splitCommand(raw)
+	if name == "" {
+		return nil, nil
+	}
+	if _, ok := lookupSetting(name); !ok {
+		return nil, errInvalidArguments
+	}
+	if value == "" {
+		return []string{name}, nil
+	}
+	return []string{name, value}, nil
+}
+
+func (surbot *Surbot) config(ctx *Context, args []string) error {
+	settings := &ctx.Server.settings
+	if len(args) == 0 {
+		embed := NewEmbed().SetTitle("Configuration")
+		for _, setting := range guildSettings {
+			embed.AddField(fmt.Sprintf("%s: %s", setting.name, setting.get(settings)), setting.description)
+		}
+		embed.SetFooter(fmt.Sprintf("Use %sconfig <setting> <value|%s> to change a setting", ctx.Prefix, resetValue))
+		return ctx.SendEmbed(embed.MessageEmbed)
+	}
+
+	setting, _ := lookupSetting(args[0])
+	if len(args) == 1 {
+		return ctx.Send(fmt.Sprintf("%s is %s", setting.name, setting.get(settings)))
+	}
+
+	var err error
+	updated := *settings
+	if strings.EqualFold(args[1], resetValue) {
+		resetSetting(&updated, setting.name)
+	} else {
+		err = setting.set(&updated, args[1])
+		if err != nil {
+			return ctx.SendEmbed(NewErrorEmbed("Invalid value", "Could not change %s, %s", setting.name, err))
+		}
+	}
+	*settings = updated
+	ctx.Server.applySettings()
+	err = surbot.saveServer(ctx.Server)
+	if err != nil {
+		logger.Log.Warningf("could not save settings, err=%v", err)
+	}
+	return ctx.Send(fmt.Sprintf("%s set to %s", setting.name, setting.get(settings)))
+}
+
+// resetSetting restores the default of the setting with the given name
+func resetSetting(settings *storage.Settings, name string) {
+	switch name {
+	case "prefix":
+		settings.Prefix = ""
+	case "djrole":
+		settings.DJRoleID = ""
+	case "volume":
+		settings.DefaultVolume = 0
+	case "timeout":
+		settings.IdleTimeout = 0
+	case "nowplaying":
+		settings.NowPlayingChannelID = ""
+	case "maxqueue":
+		settings.MaxQueueLength = 0
+	case "maxusersongs":
+		settings.MaxUserSongs = 0
+	case "fairqueue":
+		settings.FairQueue = false
+	case "skipratio":
+		settings.SkipRatio = 0
+	}
+}
+
+// prefix returns the command prefix of the server, or fallback if none is set
+func (server *Server) prefix(fallback string) string {
+	if server.settings.Prefix != "" {
+		return server.settings.Prefix
+	}
+	return fallback
+}
+
+// applySettings updates the voice of the server to match its settings
+func (server *Server) applySettings() {
+	server.voice.idleTimeout = time.Duration(timeout) * time.Minute
+	if server.settings.IdleTimeout > 0 {
+		server.voice.idleTimeout = time.Duration(server.settings.IdleTimeout) * time.Minute
+	}
+	server.voice.announceChannel = server.settings.NowPlayingChannelID
+	server.voice.music.SetFair(server.settings.FairQueue)
+}
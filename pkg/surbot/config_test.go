@@ -0,0 +1,75 @@
+package surbot
+
+import (
+	"reflect"
+	"testing"
+
+	"gitlab.com/sajfer/surbot/pkg/storage"
+)
+
+func TestGuildSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		setting string
+		value   string
+		want    storage.Settings
+		wantErr bool
+	}{
+		{name: "prefix", setting: "prefix", value: "?", want: storage.Settings{Prefix: "?"}},
+		{name: "long prefix", setting: "prefix", value: "toolong", wantErr: true},
+		{name: "role mention", setting: "djrole", value: "<@&123>", want: storage.Settings{DJRoleID: "123"}},
+		{name: "role id", setting: "djrole", value: "123", want: storage.Settings{DJRoleID: "123"}},
+		{name: "role name", setting: "djrole", value: "DJ", wantErr: true},
+		{name: "volume", setting: "volume", value: "80%", want: storage.Settings{DefaultVolume: 80}},
+		{name: "volume too high", setting: "volume", value: "201", wantErr: true},
+		{name: "timeout", setting: "timeout", value: "10", want: storage.Settings{IdleTimeout: 10}},
+		{name: "zero timeout", setting: "timeout", value: "0", wantErr: true},
+		{name: "channel mention", setting: "nowplaying", value: "<#456>", want: storage.Settings{NowPlayingChannelID: "456"}},
+		{name: "max queue", setting: "maxqueue", value: "50", want: storage.Settings{MaxQueueLength: 50}},
+		{name: "max queue not a number", setting: "maxqueue", value: "many", wantErr: true},
+		{name: "max user songs", setting: "maxusersongs", value: "20", want: storage.Settings{MaxUserSongs: 20}},
+		{name: "fair queue", setting: "fairqueue", value: "on", want: storage.Settings{FairQueue: true}},
+		{name: "fair queue invalid", setting: "fairqueue", value: "maybe", wantErr: true},
+		{name: "skip ratio", setting: "skipratio", value: "75%", want: storage.Settings{SkipRatio: 75}},
+		{name: "skip ratio too high", setting: "skipratio", value: "101", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting, ok := lookupSetting(tt.setting)
+			if !ok {
+				t.Fatalf("lookupSetting(%s) did not find setting", tt.setting)
+			}
+			var got storage.Settings
+			err := setting.set(&got, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("set() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigArgs(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{raw: "", want: nil},
+		{raw: "prefix", want: []string{"prefix"}},
+		{raw: "Prefix ?", want: []string{"Prefix", "?"}},
+		{raw: "unknown 1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := configArgs(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("configArgs(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("configArgs(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
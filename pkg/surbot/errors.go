@@ -6,4 +6,9 @@ import "errors"
 var (
 	errVoiceSkippedManually = errors.New("voice: skipped audio manually")
 	errVoiceStoppedManually = errors.New("voice: stopped audio manually")
+	errVoiceNotPlaying      = errors.New("voice: not playing")
+	errVoiceRestarted       = errors.New("voice: restarted audio")
+	errVoiceSeekOutOfRange  = errors.New("voice: seek beyond the end of the song")
+	errVoiceStreamExpired   = errors.New("voice: stream of the song has expired")
+	errVoiceSeekLive        = errors.New("voice: cannot seek in a live stream")
 )
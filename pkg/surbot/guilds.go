@@ -0,0 +1,99 @@
+// Package surbot contains the main functionality for Surbot.
+package surbot
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/sajfer/discordgo"
+	"gitlab.com/sajfer/surbot/internal/logger"
+)
+
+// guildRegistry keeps track of the servers the bot is in, keyed by guild ID.
+// It is safe for concurrent use by the discord event handlers.
+type guildRegistry struct {
+	mu        sync.RWMutex
+	servers   map[string]*Server
+	newServer func(id string) *Server
+}
+
+func newGuildRegistry(newServer func(id string) *Server) *guildRegistry {
+	return &guildRegistry{servers: make(map[string]*Server), newServer: newServer}
+}
+
+// Get returns the server of a guild, creating it if it does not exist yet
+func (r *guildRegistry) Get(id string) *Server {
+	r.mu.RLock()
+	server, ok := r.servers[id]
+	r.mu.RUnlock()
+	if ok {
+		return server
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if server, ok := r.servers[id]; ok {
+		return server
+	}
+	server = r.newServer(id)
+	r.servers[id] = server
+	return server
+}
+
+// Lookup returns the server of a guild without creating it
+func (r *guildRegistry) Lookup(id string) (*Server, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	server, ok := r.servers[id]
+	return server, ok
+}
+
+// Remove forgets the server of a guild and returns it, if it existed
+func (r *guildRegistry) Remove(id string) (*Server, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	server, ok := r.servers[id]
+	delete(r.servers, id)
+	return server, ok
+}
+
+// All returns all servers ordered by guild ID
+func (r *guildRegistry) All() []*Server {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	servers := make([]*Server, 0, len(r.servers))
+	for _, server := range r.servers {
+		servers = append(servers, server)
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].id < servers[j].id })
+	return servers
+}
+
+// guildCreated is called when the bot joins a guild, or a guild becomes
+// available when the bot connects
+func (surbot *Surbot) guildCreated(_ *discordgo.Session, g *discordgo.GuildCreate) {
+	logger.Log.Debugf("Guild available: %s", g.ID)
+	surbot.servers.Get(g.ID)
+}
+
+// guildDeleted is called when the bot is removed from a guild, or the guild
+// becomes unavailable because of an outage
+func (surbot *Surbot) guildDeleted(_ *discordgo.Session, g *discordgo.GuildDelete) {
+	if g.Unavailable {
+		logger.Log.Infof("Guild %s is unavailable", g.ID)
+		return
+	}
+	server, ok := surbot.servers.Remove(g.ID)
+	if !ok {
+		return
+	}
+	logger.Log.Infof("Removed from guild %s", g.ID)
+	err := server.voice.Close()
+	if err != nil {
+		logger.Log.Warningf("could not close voice of guild %s, err=%v", g.ID, err)
+	}
+	err = surbot.storage.Delete(g.ID)
+	if err != nil {
+		logger.Log.Warningf("could not delete state of guild %s, err=%v", g.ID, err)
+	}
+}
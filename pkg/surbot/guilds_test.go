@@ -0,0 +1,55 @@
+package surbot
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestGuildRegistry(t *testing.T) {
+	var created sync.Map
+	registry := newGuildRegistry(func(id string) *Server {
+		if _, loaded := created.LoadOrStore(id, true); loaded {
+			t.Errorf("server %s created twice", id)
+		}
+		return &Server{id: id}
+	})
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				id := fmt.Sprint(i % 10)
+				if server := registry.Get(id); server.id != id {
+					t.Errorf("Get(%s) returned server %s", id, server.id)
+				}
+				_ = registry.All()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(registry.All()); got != 10 {
+		t.Errorf("All() returned %d servers, want %d", got, 10)
+	}
+	if server, ok := registry.Lookup("1"); !ok || server.id != "1" {
+		t.Errorf("Lookup() did not find server")
+	}
+	if _, ok := registry.Lookup("missing"); ok {
+		t.Errorf("Lookup() found missing server")
+	}
+	if got := len(registry.All()); got != 10 {
+		t.Errorf("All() returned %d servers after Lookup(), want %d", got, 10)
+	}
+	if _, ok := registry.Remove("1"); !ok {
+		t.Errorf("Remove() did not find server")
+	}
+	if _, ok := registry.Remove("1"); ok {
+		t.Errorf("Remove() found removed server")
+	}
+	if got := len(registry.All()); got != 9 {
+		t.Errorf("All() returned %d servers, want %d", got, 9)
+	}
+}
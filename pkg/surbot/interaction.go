@@ -0,0 +1,145 @@
+// Package surbot contains the main functionality for Surbot.
+package surbot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sajfer/discordgo"
+	"gitlab.com/sajfer/surbot/internal/logger"
+)
+
+// maxAutocompleteChoices is the number of choices discord accepts in an autocomplete response
+const maxAutocompleteChoices = 25
+
+// interactionReceived is called every time a user invokes an application command
+func (surbot *Surbot) interactionReceived(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" {
+		return
+	}
+
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		surbot.applicationCommand(s, i.Interaction)
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		surbot.autocomplete(s, i.Interaction)
+	case discordgo.InteractionMessageComponent:
+		surbot.component(s, i.Interaction)
+	}
+}
+
+// component handles buttons on messages sent by the bot, their custom IDs
+// have the form <component>:<argument>
+func (surbot *Surbot) component(s *discordgo.Session, i *discordgo.Interaction) {
+	id, arg, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	voice := surbot.checkServer(i.GuildID).voice
+
+	var err error
+	switch id {
+	case queueComponentID:
+		err = voice.queuePage(s, i, arg)
+	case searchComponentID:
+		err = surbot.searchPick(s, i, arg)
+	default:
+		return
+	}
+	if err != nil {
+		logger.Log.Warningf("could not handle component %s, err=%v", id, err)
+	}
+}
+
+func (surbot *Surbot) applicationCommand(s *discordgo.Session, i *discordgo.Interaction) {
+	data := i.ApplicationCommandData()
+	command, ok := surbot.commands.Lookup(data.Name)
+	if !ok {
+		return
+	}
+
+	// Discord requires a response within three seconds, so acknowledge the
+	// interaction before running commands that may take longer than that.
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		logger.Log.Warning("could not respond to interaction,", err)
+		return
+	}
+
+	ctx := surbot.interactionContext(s, i)
+	surbot.runCommand(ctx, command, optionsToArgs(command, data.Options))
+	if !ctx.responded {
+		err = ctx.Send(fmt.Sprintf("Ran `/%s`", data.Name))
+		if err != nil {
+			logger.Log.Warning("could not respond to interaction,", err)
+		}
+	}
+}
+
+func (surbot *Surbot) autocomplete(s *discordgo.Session, i *discordgo.Interaction) {
+	data := i.ApplicationCommandData()
+	command, ok := surbot.commands.Lookup(data.Name)
+	if !ok {
+		return
+	}
+	autocompleter, ok := command.(Autocompleter)
+	if !ok {
+		return
+	}
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, option := range data.Options {
+		if option.Focused {
+			choices = autocompleter.Autocomplete(surbot.interactionContext(s, i), option.Name, option.StringValue())
+			break
+		}
+	}
+	if len(choices) > maxAutocompleteChoices {
+		choices = choices[:maxAutocompleteChoices]
+	}
+
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	})
+	if err != nil {
+		logger.Log.Warning("could not respond to autocomplete,", err)
+	}
+}
+
+// interactionContext returns the command context of an interaction
+func (surbot *Surbot) interactionContext(s *discordgo.Session, i *discordgo.Interaction) *Context {
+	author := i.User
+	if i.Member != nil {
+		author = i.Member.User
+	}
+	return &Context{
+		Session:     s,
+		Server:      surbot.checkServer(i.GuildID),
+		GuildID:     i.GuildID,
+		ChannelID:   i.ChannelID,
+		Author:      author,
+		Member:      i.Member,
+		Prefix:      "/",
+		Interaction: i,
+	}
+}
+
+// optionsToArgs joins the option values of an interaction, in the order the
+// command declares them, into the raw argument string of the command
+func optionsToArgs(command Command, options []*discordgo.ApplicationCommandInteractionDataOption) string {
+	slash, ok := command.(SlashCommand)
+	if !ok {
+		return ""
+	}
+	values := make(map[string]string, len(options))
+	for _, option := range options {
+		values[option.Name] = fmt.Sprint(option.Value)
+	}
+	args := make([]string, 0, len(options))
+	for _, option := range slash.Options() {
+		if value, ok := values[option.Name]; ok {
+			args = append(args, value)
+		}
+	}
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,118 @@
+// Package surbot contains the main functionality for Surbot.
+package surbot
+
+import (
+	"fmt"
+
+	"github.com/sajfer/discordgo"
+	"gitlab.com/sajfer/surbot/internal/logger"
+	"gitlab.com/sajfer/surbot/internal/utils"
+)
+
+// Permission is the level of access required to run a command
+type Permission int
+
+const (
+	// PermissionEveryone allows anyone to run the command
+	PermissionEveryone Permission = iota
+	// PermissionDJ requires the DJ role of the guild, if one is configured
+	PermissionDJ
+	// PermissionAdmin requires the permission to manage the guild
+	PermissionAdmin
+)
+
+const (
+	adminPermissions = discordgo.PermissionAdministrator | discordgo.PermissionManageServer
+	// djPermissions are the permissions that grant DJ access without the DJ role
+	djPermissions = adminPermissions | discordgo.PermissionVoiceMoveMembers
+)
+
+// allowed returns whether the author of the invocation has the given permission
+func (ctx *Context) allowed(permission Permission) (bool, error) {
+	if permission == PermissionEveryone {
+		return true, nil
+	}
+	if permission == PermissionDJ && ctx.Server.settings.DJRoleID == "" {
+		return true, nil
+	}
+
+	permissions, err := ctx.permissions()
+	if err != nil {
+		return false, err
+	}
+	if permission == PermissionAdmin {
+		return permissions&adminPermissions != 0, nil
+	}
+	dj, err := ctx.isDJ(permissions)
+	if err != nil || dj {
+		return dj, err
+	}
+	return ctx.aloneWithBot(), nil
+}
+
+// isDJ returns whether the author has the DJ role of the guild, or
+// permissions that grant the same access
+func (ctx *Context) isDJ(permissions int64) (bool, error) {
+	if permissions&djPermissions != 0 {
+		return true, nil
+	}
+	djRole := ctx.Server.settings.DJRoleID
+	if djRole == "" {
+		return false, nil
+	}
+	member, err := ctx.member()
+	if err != nil {
+		return false, err
+	}
+	for _, role := range member.Roles {
+		if role == djRole {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// deny tells the author of the invocation that they lack the permission to run the command
+func (ctx *Context) deny(command Command) error {
+	if command.Permission() == PermissionAdmin {
+		return ctx.SendEmbed(NewErrorEmbed("Permission denied", "Only server administrators can use %s%s", ctx.Prefix, command.Name()))
+	}
+	role, err := utils.GetRole(ctx.Session.State, ctx.GuildID, ctx.Server.settings.DJRoleID)
+	if err != nil {
+		logger.Log.Debugf("could not find DJ role, err=%v", err)
+		role = "DJ"
+	}
+	return ctx.SendEmbed(NewErrorEmbed("Permission denied",
+		"You need the **%s** role to use %s%s, unless you are alone with the bot in its voice channel", role, ctx.Prefix, command.Name()))
+}
+
+// permissions returns the permission bitfield of the author in the invoking channel
+func (ctx *Context) permissions() (int64, error) {
+	if ctx.Interaction != nil && ctx.Interaction.Member != nil {
+		return ctx.Interaction.Member.Permissions, nil
+	}
+	return ctx.Session.UserChannelPermissions(ctx.Author.ID, ctx.ChannelID)
+}
+
+// member returns the guild member of the author
+func (ctx *Context) member() (*discordgo.Member, error) {
+	if ctx.Member != nil {
+		return ctx.Member, nil
+	}
+	member, err := ctx.Session.State.Member(ctx.GuildID, ctx.Author.ID)
+	if err == nil {
+		return member, nil
+	}
+	member, err = ctx.Session.GuildMember(ctx.GuildID, ctx.Author.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get guild member, err=%w", err)
+	}
+	return member, nil
+}
+
+// aloneWithBot returns whether the author is the only listener in the voice
+// channel of the bot
+func (ctx *Context) aloneWithBot() bool {
+	listeners := ctx.Server.voice.listeners(ctx.Session)
+	return len(listeners) == 1 && listeners[0] == ctx.Author.ID
+}
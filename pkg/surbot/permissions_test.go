@@ -0,0 +1,18 @@
+package surbot
+
+import (
+	"testing"
+
+	"github.com/sajfer/discordgo"
+)
+
+func TestContext_AllowedWithoutDJRole(t *testing.T) {
+	// The permissions are not looked up, the context has no session to do so
+	ctx := &Context{Server: &Server{}, Author: &discordgo.User{ID: "1"}}
+	for _, permission := range []Permission{PermissionEveryone, PermissionDJ} {
+		allowed, err := ctx.allowed(permission)
+		if err != nil || !allowed {
+			t.Errorf("allowed(%d) = %v, error = %v, want true", permission, allowed, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Package surbot contains the main functionality for Surbot.
+package surbot
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/sajfer/discordgo"
+	"gitlab.com/sajfer/surbot/internal/utils"
+	"gitlab.com/sajfer/surbot/pkg/music"
+)
+
+const (
+	queuePageSize    = 10
+	queueTitleLength = 60
+	// queueComponentID prefixes the custom ID of the queue navigation buttons
+	queueComponentID = "queue"
+)
+
+// queuePages returns the number of pages needed to show the queue
+func queuePages(songs []*music.Song) int {
+	pages := (len(songs) + queuePageSize - 1) / queuePageSize
+	if pages == 0 {
+		return 1
+	}
+	return pages
+}
+
+// queueEmbed returns one page of the queue and the buttons navigating between pages
+func (voice *Voice) queueEmbed(page int) (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	current := voice.music.CurrentSong()
+	songs := voice.music.Snapshot()
+	pages := queuePages(songs)
+	if page < 0 {
+		page = 0
+	}
+	if page >= pages {
+		page = pages - 1
+	}
+
+	embed := NewEmbed().SetTitle("Queue")
+	if current == nil && len(songs) == 0 {
+		embed.AddField("No songs queued", "Use !play <youtube link|spotify link> to queue a song")
+		return embed.MessageEmbed, nil
+	}
+
+	// wait is how long until the song being listed starts playing
+	var wait float64
+	if song := current; song != nil {
+		elapsed := voice.Position().Seconds()
+		wait = song.Duration - elapsed
+		if song.Live {
+			wait = math.Inf(1)
+		}
+		embed.AddField("Now playing", fmt.Sprintf("%s `[%s / %s]`%s", shortTitle(song.Title), utils.SecondsToHuman(elapsed), songDuration(song), requestedBy(song)))
+	}
+
+	var songList strings.Builder
+	for i, song := range songs {
+		if i >= page*queuePageSize && i < (page+1)*queuePageSize {
+			songList.WriteString(fmt.Sprintf("%d. %s `[%s]` plays in %s%s\n", i+1, shortTitle(song.Title), songDuration(song), humanDuration(wait), requestedBy(song)))
+		}
+		wait += song.Duration
+		if song.Live {
+			wait = math.Inf(1)
+		}
+	}
+	if songList.Len() > 0 {
+		embed.SetDescription(songList.String())
+	}
+
+	footer := fmt.Sprintf("Page %d/%d | %d songs | %s total", page+1, pages, len(songs), humanDuration(wait))
+	if loop := voice.music.Loop(); loop != music.LoopOff {
+		footer += fmt.Sprintf(" | Loop: %s", loop)
+	}
+	if voice.music.Fair() {
+		footer += " | Fair queue"
+	}
+	embed.SetFooter(footer)
+
+	if pages == 1 {
+		return embed.MessageEmbed, nil
+	}
+	buttons := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Previous",
+				Style:    discordgo.SecondaryButton,
+				Disabled: page == 0,
+				CustomID: fmt.Sprintf("%s:%d", queueComponentID, page-1),
+			},
+			discordgo.Button{
+				Label:    "Next",
+				Style:    discordgo.SecondaryButton,
+				Disabled: page == pages-1,
+				CustomID: fmt.Sprintf("%s:%d", queueComponentID, page+1),
+			},
+		}},
+	}
+	return embed.MessageEmbed, buttons
+}
+
+// queuePage handles the navigation buttons of the queue embed
+func (voice *Voice) queuePage(s *discordgo.Session, i *discordgo.Interaction, arg string) error {
+	page, err := strconv.Atoi(arg)
+	if err != nil {
+		return err
+	}
+	embed, buttons := voice.queueEmbed(page)
+	return s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: buttons,
+		},
+	})
+}
+
+// songDuration returns the duration of a song, live streams have no end
+func songDuration(song *music.Song) string {
+	if song.Live {
+		return "∞ live"
+	}
+	return utils.SecondsToHuman(song.Duration)
+}
+
+// humanDuration returns a duration in seconds that is infinite after a live
+// stream in the queue
+func humanDuration(seconds float64) string {
+	if math.IsInf(seconds, 1) {
+		return "∞"
+	}
+	return utils.SecondsToHuman(seconds)
+}
+
+// requestedBy returns who requested the song, for appending to queue lines
+func requestedBy(song *music.Song) string {
+	if song.RequesterName == "" {
+		return ""
+	}
+	return fmt.Sprintf(", requested by %s", song.RequesterName)
+}
+
+// shortTitle shortens long song titles to keep queue lines on one row
+func shortTitle(title string) string {
+	runes := []rune(title)
+	if len(runes) <= queueTitleLength {
+		return title
+	}
+	return string(runes[:queueTitleLength-1]) + "…"
+}
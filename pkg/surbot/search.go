@@ -0,0 +1,199 @@
+// Package surbot contains the main functionality for Surbot.
+package surbot
+
+import (
+	"crypto/rand"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sajfer/discordgo"
+	"gitlab.com/sajfer/surbot/internal/logger"
+	"gitlab.com/sajfer/surbot/pkg/youtube"
+)
+
+const (
+	searchResults = 5
+	searchTimeout = time.Minute
+	// searchComponentID prefixes the custom ID of the search result buttons,
+	// which have the form search:<search>:<result>
+	searchComponentID = "search"
+	searchCancel      = "cancel"
+)
+
+// pendingSearch is a search waiting for its author to pick a result
+type pendingSearch struct {
+	ctx     *Context
+	query   string
+	results []*youtube.SearchResult
+}
+
+// searchRegistry keeps track of the searches waiting for a pick
+type searchRegistry struct {
+	mu       sync.Mutex
+	searches map[string]*pendingSearch
+}
+
+func newSearchRegistry() *searchRegistry {
+	return &searchRegistry{searches: make(map[string]*pendingSearch)}
+}
+
+// add registers a search and returns its ID
+func (r *searchRegistry) add(search *pendingSearch) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Random IDs keep buttons from before a restart from picking in new searches
+	id := rand.Text()
+	r.searches[id] = search
+	return id
+}
+
+// get returns a search that is waiting for a pick
+func (r *searchRegistry) get(id string) (*pendingSearch, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	search, ok := r.searches[id]
+	return search, ok
+}
+
+// remove removes a search, it returns false if it had already been removed
+func (r *searchRegistry) remove(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.searches[id]
+	delete(r.searches, id)
+	return ok
+}
+
+// search lists the top youtube videos matching the query, the author picks
+// which one to queue with the buttons below the list
+func (surbot *Surbot) search(ctx *Context, args []string) error {
+	results := surbot.musicClients.Youtube.SearchVideos(args[0], searchResults)
+	if len(results) == 0 {
+		return ctx.SendEmbed(NewErrorEmbed("No results", "Did not find any videos matching %s", args[0]))
+	}
+
+	search := &pendingSearch{ctx: ctx, query: args[0], results: results}
+	id := surbot.searches.add(search)
+	message, err := ctx.SendComplex(&discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{search.embed()},
+		Components: searchButtons(id, len(results)),
+	})
+	if err != nil {
+		surbot.searches.remove(id)
+		return err
+	}
+
+	time.AfterFunc(searchTimeout, func() {
+		if !surbot.searches.remove(id) {
+			return
+		}
+		embed := NewEmbed().
+			SetTitle("Search timed out").
+			SetDescription(fmt.Sprintf("No result was picked for %s", search.query)).MessageEmbed
+		edit := discordgo.NewMessageEdit(message.ChannelID, message.ID)
+		edit.Embeds = &[]*discordgo.MessageEmbed{embed}
+		edit.Components = &[]discordgo.MessageComponent{}
+		_, err := ctx.Session.ChannelMessageEditComplex(edit)
+		if err != nil {
+			logger.Log.Warningf("could not update search message, err=%v", err)
+		}
+	})
+	return nil
+}
+
+// embed returns the list of search results
+func (search *pendingSearch) embed() *discordgo.MessageEmbed {
+	var list strings.Builder
+	for i, result := range search.results {
+		list.WriteString(fmt.Sprintf("%d. %s", i+1, shortTitle(html.UnescapeString(result.Title))))
+		if result.Channel != "" {
+			list.WriteString(fmt.Sprintf(" by %s", html.UnescapeString(result.Channel)))
+		}
+		if result.Duration != "" {
+			list.WriteString(fmt.Sprintf(" `[%s]`", result.Duration))
+		}
+		list.WriteString("\n")
+	}
+	return NewEmbed().
+		SetTitle(fmt.Sprintf("Results for %s", search.query)).
+		SetDescription(list.String()).
+		SetFooter(fmt.Sprintf("%s can pick a result within %d seconds", search.ctx.authorName(), int(searchTimeout.Seconds()))).MessageEmbed
+}
+
+// searchButtons returns a numbered button for each result and a cancel button
+func searchButtons(id string, results int) []discordgo.MessageComponent {
+	numbers := make([]discordgo.MessageComponent, 0, results)
+	for i := 0; i < results; i++ {
+		numbers = append(numbers, discordgo.Button{
+			Label:    strconv.Itoa(i + 1),
+			Style:    discordgo.PrimaryButton,
+			CustomID: fmt.Sprintf("%s:%s:%d", searchComponentID, id, i),
+		})
+	}
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: numbers},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Cancel",
+				Style:    discordgo.DangerButton,
+				CustomID: fmt.Sprintf("%s:%s:%s", searchComponentID, id, searchCancel),
+			},
+		}},
+	}
+}
+
+// searchPick handles the buttons of the search results, only the author of
+// the search can pick a result
+func (surbot *Surbot) searchPick(s *discordgo.Session, i *discordgo.Interaction, arg string) error {
+	id, choice, _ := strings.Cut(arg, ":")
+	search, ok := surbot.searches.get(id)
+	if !ok {
+		return respondEphemeral(s, i, "This search has expired")
+	}
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	if user == nil || user.ID != search.ctx.Author.ID {
+		return respondEphemeral(s, i, fmt.Sprintf("Only %s can pick a result of this search", search.ctx.authorName()))
+	}
+
+	index, err := strconv.Atoi(choice)
+	if choice != searchCancel && (err != nil || index < 0 || index >= len(search.results)) {
+		return fmt.Errorf("invalid search result %s", choice)
+	}
+	if !surbot.searches.remove(id) {
+		return respondEphemeral(s, i, "This search has expired")
+	}
+
+	embed := NewEmbed().SetTitle("Search cancelled").SetDescription(fmt.Sprintf("Nothing was queued for %s", search.query))
+	if choice != searchCancel {
+		embed = NewEmbed().SetTitle("Picked").SetDescription(html.UnescapeString(search.results[index].Title))
+	}
+	err = s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed.MessageEmbed},
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+	if err != nil || choice == searchCancel {
+		return err
+	}
+	return surbot.play(search.ctx, []string{search.results[index].Path})
+}
+
+// respondEphemeral responds to an interaction with a message only its user sees
+func respondEphemeral(s *discordgo.Session, i *discordgo.Interaction, content string) error {
+	return s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
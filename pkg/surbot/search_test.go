@@ -0,0 +1,66 @@
+package surbot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sajfer/discordgo"
+	"gitlab.com/sajfer/surbot/pkg/youtube"
+)
+
+func TestSearchRegistry(t *testing.T) {
+	registry := newSearchRegistry()
+	first := &pendingSearch{query: "first"}
+	second := &pendingSearch{query: "second"}
+	firstID := registry.add(first)
+	secondID := registry.add(second)
+	if firstID == secondID {
+		t.Fatalf("add() returned the same ID %s twice", firstID)
+	}
+
+	if got, ok := registry.get(firstID); !ok || got != first {
+		t.Errorf("get() = %v, %v, want the first search", got, ok)
+	}
+	if !registry.remove(firstID) {
+		t.Error("remove() = false, want true")
+	}
+	if registry.remove(firstID) {
+		t.Error("remove() of a removed search = true, want false")
+	}
+	if _, ok := registry.get(firstID); ok {
+		t.Error("get() found a removed search")
+	}
+	if got, ok := registry.get(secondID); !ok || got != second {
+		t.Errorf("get() = %v, %v, want the second search", got, ok)
+	}
+}
+
+func TestSearchButtons(t *testing.T) {
+	rows := searchButtons("abc", 3)
+	var ids []string
+	for _, row := range rows {
+		for _, component := range row.(discordgo.ActionsRow).Components {
+			ids = append(ids, component.(discordgo.Button).CustomID)
+		}
+	}
+	want := []string{"search:abc:0", "search:abc:1", "search:abc:2", "search:abc:cancel"}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Errorf("searchButtons() custom IDs = %v, want %v", ids, want)
+	}
+}
+
+func TestPendingSearch_Embed(t *testing.T) {
+	search := &pendingSearch{
+		ctx:   &Context{Author: &discordgo.User{Username: "user"}},
+		query: "song",
+		results: []*youtube.SearchResult{
+			{Title: "Don&#39;t Stop", Channel: "Band &amp; Co", Duration: "3m31s"},
+			{Title: "Song (Live)"},
+		},
+	}
+	embed := search.embed()
+	want := "1. Don't Stop by Band & Co `[3m31s]`\n2. Song (Live)\n"
+	if embed.Description != want {
+		t.Errorf("embed() description = %q, want %q", embed.Description, want)
+	}
+}
@@ -0,0 +1,132 @@
+// Package surbot contains the main functionality for Surbot.
+package surbot
+
+import (
+	"fmt"
+	"sync"
+
+	"gitlab.com/sajfer/surbot/pkg/music"
+)
+
+// skipVotes tracks the users that voted to skip the current song
+type skipVotes struct {
+	mu    sync.Mutex
+	song  *music.Song
+	users map[string]bool
+}
+
+// reset discards all votes and starts tracking votes for song
+func (v *skipVotes) reset(song *music.Song) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.song = song
+	v.users = make(map[string]bool)
+}
+
+// add registers the vote of a user for skipping song, votes for any other
+// song are discarded
+func (v *skipVotes) add(song *music.Song, userID string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.song != song || v.users == nil {
+		v.song = song
+		v.users = make(map[string]bool)
+	}
+	v.users[userID] = true
+}
+
+// count returns how many of the given users voted to skip song
+func (v *skipVotes) count(song *music.Song, users []string) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.song != song {
+		return 0
+	}
+	count := 0
+	for _, user := range users {
+		if v.users[user] {
+			count++
+		}
+	}
+	return count
+}
+
+// requiredVotes returns the number of votes needed to skip a song when
+// ratio percent of the listeners must agree
+func requiredVotes(listeners, ratio int) int {
+	required := (listeners*ratio + 99) / 100
+	if required < 1 {
+		return 1
+	}
+	return required
+}
+
+// canForceSkip returns whether the author may skip the current song without a vote
+func (ctx *Context) canForceSkip(song *music.Song) (bool, error) {
+	if song != nil && song.RequesterID == ctx.Author.ID {
+		return true, nil
+	}
+	return ctx.allowed(PermissionDJ)
+}
+
+func skip(ctx *Context, _ []string) error {
+	voice := ctx.Voice()
+	allowed, err := ctx.canForceSkip(voice.music.CurrentSong())
+	if err != nil {
+		return fmt.Errorf("could not check permissions, err=%w", err)
+	}
+	if !allowed {
+		return ctx.SendEmbed(NewErrorEmbed("Permission denied",
+			"Only DJs and the requester of the song can skip it, use %svoteskip to vote for skipping it", ctx.Prefix))
+	}
+	return voice.Skip()
+}
+
+func (surbot *Surbot) voteSkip(ctx *Context, _ []string) error {
+	voice := ctx.Voice()
+	song := voice.music.CurrentSong()
+	if !voice.isPlaying() || song == nil {
+		return ctx.SendEmbed(NewErrorEmbed("Nothing to skip", "No song is playing"))
+	}
+
+	listeners := voice.listeners(ctx.Session)
+	listening := false
+	for _, listener := range listeners {
+		if listener == ctx.Author.ID {
+			listening = true
+			break
+		}
+	}
+	if !listening {
+		return ctx.SendEmbed(NewErrorEmbed("Not listening", "Join the voice channel of the bot to vote"))
+	}
+
+	force := song.RequesterID == ctx.Author.ID
+	if !force {
+		permissions, err := ctx.permissions()
+		if err != nil {
+			return fmt.Errorf("could not check permissions, err=%w", err)
+		}
+		force, err = ctx.isDJ(permissions)
+		if err != nil {
+			return fmt.Errorf("could not check permissions, err=%w", err)
+		}
+	}
+
+	ratio := ctx.Server.settings.SkipRatio
+	if ratio == 0 {
+		ratio = defaultSkipRatio
+	}
+	voice.skipVotes.add(song, ctx.Author.ID)
+	votes := voice.skipVotes.count(song, listeners)
+	required := requiredVotes(len(listeners), ratio)
+	if !force && votes < required {
+		return ctx.Send(fmt.Sprintf("Voted to skip **%s**, %d/%d votes", song.Title, votes, required))
+	}
+
+	err := ctx.Send(fmt.Sprintf("Skipping **%s**", song.Title))
+	if err != nil {
+		return err
+	}
+	return voice.Skip()
+}
@@ -0,0 +1,60 @@
+package surbot
+
+import (
+	"testing"
+
+	"gitlab.com/sajfer/surbot/pkg/music"
+)
+
+func TestRequiredVotes(t *testing.T) {
+	tests := []struct {
+		listeners int
+		ratio     int
+		want      int
+	}{
+		{listeners: 0, ratio: 50, want: 1},
+		{listeners: 1, ratio: 50, want: 1},
+		{listeners: 2, ratio: 50, want: 1},
+		{listeners: 3, ratio: 50, want: 2},
+		{listeners: 4, ratio: 75, want: 3},
+		{listeners: 5, ratio: 100, want: 5},
+	}
+	for _, tt := range tests {
+		if got := requiredVotes(tt.listeners, tt.ratio); got != tt.want {
+			t.Errorf("requiredVotes(%d, %d) = %d, want %d", tt.listeners, tt.ratio, got, tt.want)
+		}
+	}
+}
+
+func TestSkipVotes(t *testing.T) {
+	first := &music.Song{Title: "first"}
+	second := &music.Song{Title: "second"}
+	votes := &skipVotes{}
+	listeners := []string{"a", "b", "c"}
+
+	votes.reset(first)
+	votes.add(first, "a")
+	votes.add(first, "a")
+	votes.add(first, "d")
+	if got := votes.count(first, listeners); got != 1 {
+		t.Errorf("count() = %d, want %d", got, 1)
+	}
+
+	votes.add(first, "b")
+	if got := votes.count(first, listeners); got != 2 {
+		t.Errorf("count() = %d, want %d", got, 2)
+	}
+	if got := votes.count(second, listeners); got != 0 {
+		t.Errorf("count() of another song = %d, want %d", got, 0)
+	}
+
+	votes.add(second, "c")
+	if got := votes.count(second, listeners); got != 1 {
+		t.Errorf("count() after track change = %d, want %d", got, 1)
+	}
+
+	votes.reset(second)
+	if got := votes.count(second, listeners); got != 0 {
+		t.Errorf("count() after reset = %d, want %d", got, 0)
+	}
+}
@@ -0,0 +1,98 @@
+// Package surbot contains the main functionality for Surbot.
+package surbot
+
+import (
+	"time"
+
+	"github.com/sajfer/discordgo"
+	"gitlab.com/sajfer/surbot/internal/logger"
+	"gitlab.com/sajfer/surbot/pkg/storage"
+)
+
+// stateInterval is how often the state of all servers is persisted
+const stateInterval = 30 * time.Second
+
+// saveServer persists the state of a server
+func (surbot *Surbot) saveServer(server *Server) error {
+	voice := server.voice
+	guild := &storage.Guild{
+		ID:             server.id,
+		Volume:         server.volume,
+		VolumeSet:      server.volumeSet,
+		TextChannelID:  voice.channelID,
+		VoiceChannelID: voice.voiceChannelID,
+		CurrentSong:    voice.music.CurrentSong(),
+		Queue:          voice.music.Snapshot(),
+		Loop:           voice.music.Loop(),
+		Settings:       server.settings,
+	}
+	if guild.CurrentSong != nil {
+		guild.Position = voice.Position().Seconds()
+	}
+	return surbot.storage.Save(guild)
+}
+
+// saveServers persists the state of all servers
+func (surbot *Surbot) saveServers() {
+	for _, server := range surbot.servers.All() {
+		err := surbot.saveServer(server)
+		if err != nil {
+			logger.Log.Warningf("could not save state of server %s, err=%v", server.id, err)
+		}
+	}
+}
+
+// saveState persists the state of all servers periodically until stop is closed
+func (surbot *Surbot) saveState(stop chan bool) {
+	ticker := time.NewTicker(stateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			surbot.saveServers()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// restoreServers restores the queues that were stored before the last
+// shutdown, rejoining the voice channels and continuing playback if enabled
+func (surbot *Surbot) restoreServers(s *discordgo.Session) {
+	guilds, err := surbot.storage.List()
+	if err != nil {
+		logger.Log.Warningf("could not load stored state, err=%v", err)
+		return
+	}
+	for _, guild := range guilds {
+		if guild.CurrentSong == nil && len(guild.Queue) == 0 {
+			continue
+		}
+		voice := surbot.checkServer(guild.ID).voice
+		voice.SetSession(s)
+		voice.SetTextChannel(guild.TextChannelID)
+		voice.music.SetLoop(guild.Loop)
+		voice.music.Restore(guild.CurrentSong, guild.Queue)
+		if guild.CurrentSong != nil {
+			voice.mu.Lock()
+			voice.resumeAt = time.Duration(guild.Position * float64(time.Second))
+			voice.mu.Unlock()
+		}
+		logger.Log.Infof("Restored %d songs for server %s", voice.music.Len(), guild.ID)
+
+		if !surbot.rejoin || guild.VoiceChannelID == "" {
+			continue
+		}
+		err = voice.Connect(guild.VoiceChannelID, guild.ID, false, true)
+		if err != nil {
+			logger.Log.Warningf("could not rejoin voice channel, err=%v", err)
+			continue
+		}
+		go func() {
+			err := voice.play()
+			if err != nil {
+				logger.Log.Warningf("could not continue playing, err=%v", err)
+			}
+		}()
+	}
+}
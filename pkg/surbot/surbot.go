@@ -2,19 +2,18 @@
 package surbot
 
 import (
-	"crypto/rand"
+	"errors"
 	"log"
-	"math/big"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
-	"time"
 
 	"github.com/sajfer/discordgo"
 	"gitlab.com/sajfer/surbot/internal/logger"
-	"gitlab.com/sajfer/surbot/internal/utils"
 	"gitlab.com/sajfer/surbot/pkg/music"
+	"gitlab.com/sajfer/surbot/pkg/storage"
+	"gitlab.com/sajfer/surbot/pkg/youtube"
 )
 
 // Surbot contain basic information about the bot
@@ -22,32 +21,77 @@ type Surbot struct {
 	token        string
 	prefix       string
 	musicClients *music.MusicClients
-	servers      []*Server
+	commands     *Registry
+	storage      storage.Storage
+	rejoin       bool
+	servers      *guildRegistry
+	searches     *searchRegistry
 }
 
 type Server struct {
-	id    string
-	voice *Voice
+	id        string
+	voice     *Voice
+	volume    int
+	volumeSet bool
+	settings  storage.Settings
 }
 
 // NewSurbot return an instance of surbot
-func NewSurbot(token, youtubeAPI, clientID, clientSecret, prefix string) Surbot {
+func NewSurbot(token, youtubeAPI, clientID, clientSecret, prefix string, store storage.Storage) *Surbot {
 	logger.Log.Debug("NewSurbot")
 	musicClients := music.NewMusicClients(youtubeAPI, clientID, clientSecret)
-	return Surbot{token: token, prefix: prefix, musicClients: musicClients}
+	surbot := &Surbot{token: token, prefix: prefix, musicClients: musicClients, commands: NewRegistry(), storage: store, searches: newSearchRegistry()}
+	surbot.servers = newGuildRegistry(surbot.newServer)
+	err := surbot.registerCommands()
+	if err != nil {
+		logger.Log.Fatal("could not register commands,", err)
+	}
+	return surbot
+}
+
+// SetRejoin sets whether the bot rejoins the voice channels it was connected
+// to before a restart, and continues playing the restored queue
+func (surbot *Surbot) SetRejoin(rejoin bool) {
+	surbot.rejoin = rejoin
+}
+
+// SetSpotifyMaxTracks sets how many tracks are queued from a spotify playlist or album
+func (surbot *Surbot) SetSpotifyMaxTracks(maxTracks int) {
+	surbot.musicClients.Spotify.SetMaxTracks(maxTracks)
+}
+
+// SetLibrary indexes the audio files in dir, they are played with play file:<name>
+func (surbot *Surbot) SetLibrary(dir string) error {
+	return surbot.musicClients.AddLibrary(dir)
+}
+
+// SetInvidious searches youtube with an invidious instance when there is no
+// youtube API key or its quota has run out
+func (surbot *Surbot) SetInvidious(url string) {
+	surbot.musicClients.Youtube.SetFallback(youtube.NewInvidiousSearcher(url))
 }
 
 // checkServer returns the server configuration of current server
 func (surbot *Surbot) checkServer(serverID string) *Server {
-	for _, t := range surbot.servers {
-		if t.id == serverID {
-			return t
-		}
-	}
+	return surbot.servers.Get(serverID)
+}
+
+// newServer returns the server configuration of a guild, loading its stored state
+func (surbot *Surbot) newServer(serverID string) *Server {
 	musicClient := music.NewMusic()
-	voice := NewVoice(musicClient)
-	server := &Server{id: serverID, voice: voice}
-	surbot.servers = append(surbot.servers, server)
+	voice := NewVoice(musicClient, surbot.musicClients)
+	server := &Server{id: serverID, voice: voice, volume: defaultVolume}
+	guild, err := surbot.storage.Load(serverID)
+	if err == nil {
+		server.volume = guild.Volume
+		server.volumeSet = guild.VolumeSet
+		server.settings = guild.Settings
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		logger.Log.Warningf("could not load server state, err=%v", err)
+	}
+	voice.volume = server.volume
+	voice.radio = surbot.musicClients.Radio
+	server.applySettings()
 	return server
 }
 
@@ -60,184 +104,89 @@ func (surbot *Surbot) messageReceived(s *discordgo.Session, m *discordgo.Message
 	if m.Author.ID == s.State.User.ID {
 		return
 	}
-
-	if !strings.HasPrefix(m.Content, surbot.prefix) {
+	// Commands only run in guilds, direct messages have no guild
+	if m.GuildID == "" {
 		return
 	}
 
-	server := surbot.checkServer(m.GuildID)
-	message := strings.TrimPrefix(m.Content, surbot.prefix)
-
-	// If the message is "ping" reply with "Pong!"
-	if message == "ping" {
-		_, err := s.ChannelMessageSend(m.ChannelID, "Pong!")
-		if err != nil {
-			logger.Log.Warning("could not send message,", err)
-		}
-		return
+	// Guilds are loaded when they become available, a guild that is not
+	// loaded yet is only loaded for messages that look like commands
+	server, loaded := surbot.servers.Lookup(m.GuildID)
+	prefix := surbot.prefix
+	if loaded {
+		prefix = server.prefix(surbot.prefix)
 	}
-
-	if message == "help" {
-		utils.PrintHelp(s, m.ChannelID)
+	if !strings.HasPrefix(m.Content, prefix) {
+		return
 	}
-
-	if message == "chuck" {
-		joke := utils.GetChuckJoke()
-		_, err := s.ChannelMessageSend(m.ChannelID, joke)
-		if err != nil {
-			logger.Log.Warning("could not send message,", err)
+	if !loaded {
+		server = surbot.checkServer(m.GuildID)
+		prefix = server.prefix(surbot.prefix)
+		if !strings.HasPrefix(m.Content, prefix) {
+			return
 		}
-		return
 	}
 
-	if message == "playing" {
-		voice := server.voice
-		voice.channelID = m.ChannelID
-		voice.SetSession(s)
-		voice.NowPlaying()
-		return
-	}
+	name, raw := splitCommand(strings.TrimPrefix(m.Content, prefix))
 
-	if message == "shuffle" {
-		server.voice.music.Shuffle()
+	command, ok := surbot.commands.Lookup(name)
+	if !ok {
 		return
 	}
 
-	if strings.HasPrefix(message, "play") {
-		logger.Log.Debugln("Playing music")
-		voice := server.voice
-		voice.channelID = m.ChannelID
-		voice.SetSession(s)
-		query := strings.TrimPrefix(message, "play")
-		query = strings.ReplaceAll(query, " ", "")
-
-		playlist, err := surbot.musicClients.FetchSong(query)
-		if err != nil {
-			logger.Log.Warningf("could not fetch song information, err=%v", err)
-		}
-		err = voice.music.AddToQueue(*playlist)
-		if err != nil {
-			logger.Log.Warningf("could not add songs to playlist, err=%v", err)
-		}
+	ctx := &Context{Session: s, Server: server, GuildID: m.GuildID, ChannelID: m.ChannelID, Author: m.Author, Member: m.Member, Prefix: prefix}
+	surbot.runCommand(ctx, command, raw)
+}
 
-		err = voice.Start(m)
+// runCommand parses the arguments of a command and runs it
+func (surbot *Surbot) runCommand(ctx *Context, command Command, raw string) {
+	allowed, err := ctx.allowed(command.Permission())
+	if err != nil {
+		logger.Log.Warningf("could not check permissions for command %s, err=%v", command.Name(), err)
+		err = ctx.SendEmbed(NewErrorEmbed("Could not check permissions", "Could not check whether you can use %s%s, try again later", ctx.Prefix, command.Name()))
 		if err != nil {
-			logger.Log.Warningf("could not play song, err=%s", err)
+			logger.Log.Warning("could not send message,", err)
 		}
 		return
 	}
-
-	if message == "stop" {
-		voice := server.voice
-		voice.channelID = m.ChannelID
-		voice.SetSession(s)
-		err := voice.Stop()
-		if err != nil {
-			logger.Log.Warningf("could not stop playing song, err=%s", err)
-		}
-	}
-
-	if message == "queue" {
-		voice := server.voice
-		voice.channelID = m.ChannelID
-		voice.SetSession(s)
-		err := voice.ShowQueue()
+	if !allowed {
+		err = ctx.deny(command)
 		if err != nil {
-			logger.Log.Warningf("could not show queue, err=%s", err)
+			logger.Log.Warning("could not send message,", err)
 		}
 		return
 	}
 
-	if message == "skip" {
-		voice := server.voice
-		voice.channelID = m.ChannelID
-		voice.SetSession(s)
-		err := voice.Skip()
+	args, err := command.ParseArgs(raw)
+	if err != nil {
+		err = ctx.SendEmbed(NewErrorEmbed("Invalid arguments", "Usage: %s%s %s", ctx.Prefix, command.Name(), command.Usage()))
 		if err != nil {
-			logger.Log.Warning("could not skip song")
+			logger.Log.Warning("could not send message,", err)
 		}
 		return
 	}
 
-	if message == "disconnect" {
-		voice := server.voice
-		voice.channelID = m.ChannelID
-		voice.SetSession(s)
-		err := voice.Disconnect()
+	err = command.Run(ctx, args)
+	if err != nil {
+		logger.Log.Warningf("could not run command %s, err=%v", command.Name(), err)
+		err = ctx.SendEmbed(commandErrorEmbed(ctx, command, err))
 		if err != nil {
-			logger.Log.Warning("could not disconnect")
+			logger.Log.Warning("could not send message,", err)
 		}
 	}
+}
 
-	if message == "clearQueue" {
-		voice := server.voice
-		voice.channelID = m.ChannelID
-		voice.SetSession(s)
-		err := voice.ClearQueue()
-		if err != nil {
-			logger.Log.Warning("could not clear queue")
-		}
-	}
-	if message == "rajd" {
-		currentTime := time.Now()
-		rajdChannel := "1006248135737221251"
-		for {
-			msg, err := s.ChannelMessageSend(rajdChannel, currentTime.Format("Monday 01/02"))
-			if err != nil {
-				logger.Log.Warning("could not send message,", err)
-			}
-			err = s.MessageReactionAdd(rajdChannel, msg.ID, "✅")
-			if err != nil {
-				logger.Log.Warning("could not add emote,", err)
-			}
-			err = s.MessageReactionAdd(rajdChannel, msg.ID, "❌")
-			if err != nil {
-				logger.Log.Warning("could not add emote,", err)
-			}
-			currentTime = currentTime.AddDate(0, 0, 1)
-			if currentTime.Format("Monday") == "Wednesday" {
-				break
-			}
-		}
-		return
-	}
-	if strings.HasPrefix(message, "roll") {
-		dice := strings.TrimPrefix(message, "roll")
-		dice = strings.ReplaceAll(dice, " ", "")
-		var roll *big.Int
-		var err error
-		switch dice {
-		case "d6":
-			roll, err = rand.Int(rand.Reader, big.NewInt(6)) // #nosec G404
-			if err != nil {
-				logger.Log.Warning("could not generate random number,", err)
-			}
-		case "d10":
-			roll, err = rand.Int(rand.Reader, big.NewInt(10)) // #nosec G404
-			if err != nil {
-				logger.Log.Warning("could not generate random number,", err)
-			}
-		case "d20":
-			roll, err = rand.Int(rand.Reader, big.NewInt(20)) // #nosec G404
-			if err != nil {
-				logger.Log.Warning("could not generate random number,", err)
-			}
-		case "d100":
-			roll, err = rand.Int(rand.Reader, big.NewInt(100)) // #nosec G404
-			if err != nil {
-				logger.Log.Warning("could not generate random number,", err)
-			}
-		}
-		_, err = s.ChannelMessageSend(m.ChannelID, roll.String())
-		if err != nil {
-			logger.Log.Warning("could not send message,", err)
-		}
-		return
+// commandErrorEmbed returns the reply to a command that failed, the error
+// itself is only logged since it can contain details of the bot
+func commandErrorEmbed(ctx *Context, command Command, err error) *discordgo.MessageEmbed {
+	if errors.Is(err, errVoiceNotPlaying) {
+		return NewErrorEmbed("Not playing", "No song is playing")
 	}
+	return NewErrorEmbed("Command failed", "Could not run %s%s, try again later", ctx.Prefix, command.Name())
 }
 
 // StartServer connect the server to discord
-func (surbot Surbot) StartServer() {
+func (surbot *Surbot) StartServer() {
 	discord, err := discordgo.New("Bot " + surbot.token)
 	if err != nil {
 		logger.Log.Fatal("error creating Discord session,", err)
@@ -246,6 +195,9 @@ func (surbot Surbot) StartServer() {
 
 	// Register the messageCreate func as a callback for MessageCreate events.
 	discord.AddHandler(surbot.messageReceived)
+	discord.AddHandler(surbot.interactionReceived)
+	discord.AddHandler(surbot.guildCreated)
+	discord.AddHandler(surbot.guildDeleted)
 
 	//discord.AddHandler(surbot.changedChannel)
 
@@ -256,12 +208,24 @@ func (surbot Surbot) StartServer() {
 		return
 	}
 
+	_, err = discord.ApplicationCommandBulkOverwrite(discord.State.User.ID, "", surbot.commands.ApplicationCommands())
+	if err != nil {
+		logger.Log.Warning("could not register application commands,", err)
+	}
+
+	surbot.restoreServers(discord)
+	stopSaving := make(chan bool)
+	go surbot.saveState(stopSaving)
+
 	// Wait here until CTRL-C or other term signal is received.
 	logger.Log.Info("Bot is now running. Press CTRL-C to exit.")
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 	<-sc
 
+	close(stopSaving)
+	surbot.saveServers()
+
 	// Cleanly close down the Discord session.
 	err = discord.Close()
 	if err != nil {
@@ -2,39 +2,46 @@
 package surbot
 
 import (
+	"sync"
 	"time"
 
 	"gitlab.com/sajfer/surbot/internal/logger"
 )
 
 type Timer struct {
-	timer   time.Timer
-	running bool
-	stop    chan bool
+	mu    sync.Mutex
+	timer *time.Timer
 }
 
-func (timer *Timer) initTimer(timeout time.Duration, voice Voice) {
+// initTimer disconnects the voice after timeout, unless the timer is stopped
+// or the voice is playing by then
+func (timer *Timer) initTimer(timeout time.Duration, voice *Voice) {
 	logger.Log.Debug("timer.initTimer")
-	timer.running = true
-	timer.timer = *time.NewTimer(time.Duration(timeout))
-	select {
-	case <-timer.timer.C:
+	timer.mu.Lock()
+	defer timer.mu.Unlock()
+	if timer.timer != nil {
+		timer.timer.Stop()
+	}
+	timer.timer = time.AfterFunc(timeout, func() {
+		// A timer that fires while it is being stopped must not disconnect
+		if voice.isPlaying() && !voice.isPaused() {
+			logger.Log.Debug("Idle timeout while playing, staying in channel")
+			return
+		}
 		logger.Log.Debug("Idle timeout, leaving channel")
 		err := voice.Disconnect()
 		if err != nil {
 			logger.Log.Warningf("Could not disconnect from voice channel, err=%v", err)
 		}
-	case <-timer.stop:
-		logger.Log.Debug("Timer cancelled")
-	}
+	})
 }
 
 func (timer *Timer) stopTimer() {
 	logger.Log.Debug("Stopping idle timer")
-	select {
-	case timer.stop <- true:
-	default:
+	timer.mu.Lock()
+	defer timer.mu.Unlock()
+	if timer.timer != nil {
+		timer.timer.Stop()
+		timer.timer = nil
 	}
-	timer.timer.Stop()
-	timer.running = false
 }
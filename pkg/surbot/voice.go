@@ -2,9 +2,12 @@
 package surbot
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/sajfer/dca"
@@ -12,28 +15,85 @@ import (
 	"gitlab.com/sajfer/surbot/internal/logger"
 	"gitlab.com/sajfer/surbot/internal/utils"
 	"gitlab.com/sajfer/surbot/pkg/music"
+	"gitlab.com/sajfer/surbot/pkg/radio"
 )
 
 type Voice struct {
-	VoiceChannel     *discordgo.VoiceConnection
-	EncodingSession  *dca.EncodeSession
-	StreamingSession *dca.StreamingSession
-	Session          *discordgo.Session
+	VoiceChannel *discordgo.VoiceConnection
+	Session      *discordgo.Session
+	// mu guards the playback state up to resumeAt, play runs in its own goroutine
+	mu               sync.Mutex
+	EncodingSession  encoder
+	StreamingSession streamer
 	Playing          bool
+	Paused           bool
+	active           bool
+	ending           error
+	done             chan error
+	volume           int
+	startTime        time.Duration
+	resumeAt         time.Duration
 	voiceGuildID     string
 	voiceChannelID   string
 	channelID        string
-	done             chan error
 	timer            *Timer
 	music            *music.Music
+	announceChannel  string
+	idleTimeout      time.Duration
+	skipVotes        *skipVotes
+	resolver         music.Resolver
+	radio            *radio.Radio
+	titleMu          sync.Mutex
+	streamTitle      string
+	encode           func(link string, options *dca.EncodeOptions) (encoder, error)
+	stream           func(source encoder, vc *discordgo.VoiceConnection, done chan error) streamer
+}
+
+// encoder encodes a song with ffmpeg, it is a dca.EncodeSession outside of tests
+type encoder interface {
+	dca.OpusReader
+	Stop() error
+	Cleanup()
+	FFMPEGMessages() string
+}
+
+// streamer sends an encoded song to a voice channel, it is a
+// dca.StreamingSession outside of tests
+type streamer interface {
+	SetPaused(paused bool)
+	PlaybackPosition() time.Duration
+	Finished() (bool, error)
 }
 
 var (
 	timeout = 5
 )
 
-func NewVoice(music *music.Music) *Voice {
-	return &Voice{timer: &Timer{stop: make(chan bool), running: false}, music: music}
+const (
+	// defaultVolume is the volume, in percent, used for guilds that have not set one
+	defaultVolume = 100
+	maxVolume     = 200
+	// volumeScale is the ffmpeg volume corresponding to 100 percent
+	volumeScale = 0.10
+	// maxSkippedSongs is how many songs in a row without a stream are
+	// skipped before playback stops
+	maxSkippedSongs = 3
+)
+
+func NewVoice(music *music.Music, resolver music.Resolver) *Voice {
+	return &Voice{timer: &Timer{}, music: music, resolver: resolver, volume: defaultVolume, skipVotes: &skipVotes{}, idleTimeout: time.Duration(timeout) * time.Minute, encode: encodeFile, stream: newStream}
+}
+
+func encodeFile(link string, options *dca.EncodeOptions) (encoder, error) {
+	session, err := dca.EncodeFile(link, options)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func newStream(source encoder, vc *discordgo.VoiceConnection, done chan error) streamer {
+	return dca.NewStream(source, vc, done)
 }
 
 func (voice *Voice) SetTextChannel(channel string) {
@@ -67,28 +127,13 @@ func (voice *Voice) Connect(channelId, guildId string, mute, deaf bool) error {
 func (voice *Voice) Disconnect() error {
 	logger.Log.Debug("voice.Disconnect")
 
-	if voice.Playing {
-		voice.done <- errVoiceStoppedManually
-	}
-	voice.Playing = false
+	// play stops the sessions of the song once it has been signalled
+	voice.signal(errVoiceStoppedManually)
+	voice.mu.Lock()
+	voice.Paused = false
+	voice.mu.Unlock()
 	voice.voiceChannelID = ""
 	voice.voiceGuildID = ""
-	if voice.StreamingSession != nil {
-		_, err := voice.StreamingSession.Finished()
-		if err != nil {
-			return err
-		}
-		voice.StreamingSession = nil
-	}
-
-	if voice.EncodingSession != nil {
-		err := voice.EncodingSession.Stop()
-		if err != nil {
-			return err
-		}
-		voice.EncodingSession.Cleanup()
-		voice.EncodingSession = nil
-	}
 	if voice.VoiceChannel != nil {
 		err := voice.VoiceChannel.Disconnect()
 		if err != nil {
@@ -99,53 +144,96 @@ func (voice *Voice) Disconnect() error {
 	return nil
 }
 
-func (voice *Voice) Start(m *discordgo.MessageCreate) error {
+// Close stops playback, leaves the voice channel and stops the idle timer
+func (voice *Voice) Close() error {
+	voice.music.ClearQueue()
+	err := voice.Disconnect()
+	voice.timer.stopTimer()
+	return err
+}
+
+// Start joins the voice channel of the user and plays the queue in the
+// background, it does nothing if the voice is already playing
+func (voice *Voice) Start(guildID, userID string) error {
 	logger.Log.Debug("voice.Start")
 
-	if !voice.Playing {
-		guild, err := voice.Session.State.Guild(m.GuildID)
+	if !voice.isActive() {
+		guild, err := voice.Session.State.Guild(guildID)
 		if err != nil {
 			return err
 		}
 		channelId := ""
 		for _, person := range guild.VoiceStates {
-			if person.UserID == m.Author.ID {
+			if person.UserID == userID {
 				logger.Log.Debugf("Voice channel: %s", person.ChannelID)
 				channelId = person.ChannelID
 				break
 			}
 		}
-		err = voice.Connect(channelId, m.GuildID, false, true)
+		err = voice.Connect(channelId, guildID, false, true)
 		if err != nil {
 			logger.Log.Warningf("could not join voice channel, err=%s", err)
 			return err
 		}
-		err = voice.play()
-		if err != nil {
-			logger.Log.Warningf("could not play song, err=%s", err)
-			return err
-		}
+		// play only returns once the queue is done, so the command
+		// that started playback can respond right away
+		go func() {
+			err := voice.play()
+			if err != nil {
+				logger.Log.Warningf("could not play song, err=%s", err)
+			}
+		}()
 	}
 	return nil
 }
 
+// listeners returns the IDs of the users, other than bots, in the voice channel of the bot
+func (voice *Voice) listeners(session *discordgo.Session) []string {
+	if voice.voiceChannelID == "" {
+		return nil
+	}
+	guild, err := session.State.Guild(voice.voiceGuildID)
+	if err != nil {
+		return nil
+	}
+	var listeners []string
+	for _, state := range guild.VoiceStates {
+		if state.ChannelID != voice.voiceChannelID || state.UserID == session.State.User.ID {
+			continue
+		}
+		if state.Member != nil && state.Member.User != nil && state.Member.User.Bot {
+			continue
+		}
+		listeners = append(listeners, state.UserID)
+	}
+	return listeners
+}
+
+// startIdleTimer starts the timer that disconnects the bot when it has been idle for too long
+func (voice *Voice) startIdleTimer() {
+	voice.timer.stopTimer()
+	if voice.VoiceChannel == nil {
+		return
+	}
+	voice.timer.initTimer(voice.idleTimeout, voice)
+}
+
 func (voice *Voice) stopPlaying() error {
 	err := voice.Session.UpdateListeningStatus("")
-	voice.music.CurrentSong = nil
-	go voice.timer.initTimer(time.Duration(timeout)*time.Minute, *voice)
+	voice.music.SetCurrentSong(nil)
+	voice.startIdleTimer()
 	return err
 }
 
 func (voice *Voice) getSongFromQueue() (*music.Song, error) {
-	if len(voice.music.Queue) == 0 {
+	song := voice.music.Pop()
+	if song == nil {
 		return nil, fmt.Errorf("queue is empty")
 	}
-	song := voice.music.Queue[0]
-	voice.music.Queue = voice.music.Queue[1:]
 	err := voice.Session.UpdateListeningStatus(song.Title)
 	if err != nil {
-		voice.Playing = false
-		voice.music.Queue = nil
+		voice.music.SetCurrentSong(nil)
+		voice.music.ClearQueue()
 		return nil, err
 	}
 	return song, nil
@@ -154,178 +242,495 @@ func (voice *Voice) getSongFromQueue() (*music.Song, error) {
 func (voice *Voice) play() error {
 	logger.Log.Debug("voice.Play")
 
-	if voice.Playing {
+	if !voice.activate() {
 		return nil
 	}
+	defer voice.deactivate()
 
-	voice.Playing = true
-
-	if voice.timer.running {
-		voice.timer.stopTimer()
-	}
-	song, err := voice.getSongFromQueue()
-	if err != nil {
-		return err
-	}
-
-	voice.music.CurrentSong = song
-	voice.NowPlaying()
-	logger.Log.Infof("Now playing: %s - %s", song.Artist, song.Title)
-	msg, err := voice.playRaw(*song)
-	if msg != nil {
-		if msg == errVoiceStoppedManually {
-			voice.Playing = false
+	voice.timer.stopTimer()
+	var skipped []string
+	for {
+		song, err := voice.getSongFromQueue()
+		if err != nil {
+			return err
+		}
+		resolved, err := voice.resolver.Resolve(song)
+		if err != nil {
+			logger.Log.Warningf("could not find a stream for %s, err=%v", song.Title, err)
+			voice.music.SetCurrentSong(nil)
+			voice.mu.Lock()
+			if voice.ending != errVoiceStoppedManually {
+				// A skip was meant for the song that could not be found
+				voice.ending = nil
+			}
+			voice.mu.Unlock()
+			skipped = append(skipped, song.Title)
+			if len(skipped) < maxSkippedSongs && voice.music.Len() > 0 {
+				continue
+			}
+			voice.reportSkipped(skipped, voice.music.Len() > 0)
 			return voice.stopPlaying()
 		}
-	}
-	if err != nil {
-		voice.Playing = false
-		switch err {
-		case io.ErrUnexpectedEOF:
-			if msg != errVoiceSkippedManually {
+		if len(skipped) > 0 {
+			voice.reportSkipped(skipped, false)
+			skipped = nil
+		}
+		voice.music.Replace(song, resolved)
+		song = resolved
+		go voice.prefetch()
+
+		voice.mu.Lock()
+		voice.startTime = voice.resumeAt
+		voice.resumeAt = 0
+		voice.Paused = false
+		voice.mu.Unlock()
+		voice.skipVotes.reset(song)
+		voice.setStreamTitle("")
+		voice.announceNowPlaying()
+		logger.Log.Infof("Now playing: %s - %s", song.Artist, song.Title)
+		stopWatching := func() {}
+		if song.Live && voice.radio != nil {
+			var watchCtx context.Context
+			watchCtx, stopWatching = context.WithCancel(context.Background())
+			go voice.watchStreamTitle(watchCtx, song.StreamURL)
+		}
+		msg, err := voice.playRaw(*song)
+		refreshed := false
+		for err == nil && (msg == errVoiceRestarted || msg == errVoiceStreamExpired && !refreshed) {
+			if msg == errVoiceStreamExpired {
+				refreshed = true
+				song, err = voice.refreshStream(song)
+				if err != nil {
+					logger.Log.Warningf("could not refresh stream, err=%v", err)
+					msg, err = nil, nil
+					break
+				}
+			}
+			msg, err = voice.playRaw(*song)
+		}
+		stopWatching()
+		if msg != nil {
+			if msg == errVoiceStoppedManually {
 				return voice.stopPlaying()
 			}
-		case dca.ErrVoiceConnClosed:
-			if msg != errVoiceSkippedManually {
-				_ = voice.Session.UpdateListeningStatus("")
-				voice.music.CurrentSong = nil
-				err := voice.Connect(voice.channelID, voice.VoiceChannel.GuildID, false, true)
-				if err != nil {
-					logger.Log.Warningf("could not join voice channel, err=%s", err)
-					return err
+		}
+		if err != nil {
+			switch err {
+			case io.ErrUnexpectedEOF:
+				if msg != errVoiceSkippedManually {
+					return voice.stopPlaying()
+				}
+			case dca.ErrVoiceConnClosed:
+				if msg != errVoiceSkippedManually {
+					_ = voice.Session.UpdateListeningStatus("")
+					voice.music.SetCurrentSong(nil)
+					err := voice.Connect(voice.channelID, voice.VoiceChannel.GuildID, false, true)
+					if err != nil {
+						logger.Log.Warningf("could not join voice channel, err=%s", err)
+						return err
+					}
 				}
+			default:
+				return err
 			}
-		default:
-			return err
+		}
+		voice.music.Finish(msg == errVoiceSkippedManually)
+		if voice.music.Len() == 0 {
+			err := voice.Session.UpdateListeningStatus("")
+			if err != nil {
+				return err
+			}
+			voice.startIdleTimer()
+			return nil
 		}
 	}
-	if len(voice.music.Queue) > 0 {
-		return voice.play()
-	} else {
-		err := voice.Session.UpdateListeningStatus("")
-		if err != nil {
-			return err
+}
+
+// reportSkipped tells the text channel which songs had no stream, stopped is
+// set when playback stops with songs left in the queue
+func (voice *Voice) reportSkipped(titles []string, stopped bool) {
+	msg := fmt.Sprintf("Skipped %s", strings.Join(titles, ", "))
+	if stopped {
+		msg = fmt.Sprintf("%s\nStopped after %d songs in a row, use play without a link to continue with the queue", msg, len(titles))
+	}
+	_, err := voice.Session.ChannelMessageSendEmbed(voice.channelID, NewErrorEmbed("Could not find a stream", "%s", msg))
+	if err != nil {
+		logger.Log.Warningf("could not send skipped songs, err=%v", err)
+	}
+}
+
+// refreshStream looks up the stream of the current song again after it has
+// expired
+func (voice *Voice) refreshStream(song *music.Song) (*music.Song, error) {
+	expired := *song
+	expired.StreamURL = ""
+	resolved, err := voice.resolver.Resolve(&expired)
+	if err != nil {
+		return song, err
+	}
+	voice.music.Replace(song, resolved)
+	return resolved, nil
+}
+
+// prefetch resolves the next song in the queue while the current one plays,
+// so that it can start without a gap
+func (voice *Voice) prefetch() {
+	next := voice.music.Peek()
+	if next == nil || next.Resolved() {
+		return
+	}
+	resolved, err := voice.resolver.Resolve(next)
+	if err != nil {
+		logger.Log.Debugf("could not prefetch %s, err=%v", next.Title, err)
+		return
+	}
+	voice.music.Replace(next, resolved)
+}
+
+// watchStreamTitle shows the title of the song playing on a radio station as
+// listening status, until the context is done
+func (voice *Voice) watchStreamTitle(ctx context.Context, link string) {
+	err := voice.radio.WatchTitle(ctx, link, func(title string) {
+		if ctx.Err() != nil {
+			return
 		}
-		voice.music.CurrentSong = nil
-		go voice.timer.initTimer(time.Duration(timeout)*time.Minute, *voice)
-		return nil
+		voice.setStreamTitle(title)
+		if voice.isPaused() {
+			return
+		}
+		if err := voice.Session.UpdateListeningStatus(title); err != nil {
+			logger.Log.Warningf("could not update listening status, err=%v", err)
+		}
+	})
+	if err != nil {
+		logger.Log.Debugf("stopped reading stream title, err=%v", err)
 	}
 }
 
+// setStreamTitle sets the title of the song playing on the current radio station
+func (voice *Voice) setStreamTitle(title string) {
+	voice.titleMu.Lock()
+	defer voice.titleMu.Unlock()
+	voice.streamTitle = title
+}
+
+// getStreamTitle returns the title of the song playing on the current radio
+// station, empty if it is unknown
+func (voice *Voice) getStreamTitle() string {
+	voice.titleMu.Lock()
+	defer voice.titleMu.Unlock()
+	return voice.streamTitle
+}
+
+// listeningStatus returns the status shown while the song plays
+func (voice *Voice) listeningStatus(song *music.Song) string {
+	if title := voice.getStreamTitle(); song.Live && title != "" {
+		return title
+	}
+	return song.Title
+}
+
+// streamForbidden returns whether ffmpeg was denied access to the stream,
+// which happens when the stream URL has expired
+func streamForbidden(ffmpegMessages string) bool {
+	return strings.Contains(ffmpegMessages, "403 Forbidden")
+}
+
 func (voice *Voice) playRaw(song music.Song) (error, error) {
 	logger.Log.Debug("voice.PlayRaw")
-	var err error
 
-	options := dca.StdEncodeOptions
+	options := *dca.StdEncodeOptions
 	options.RawOutput = true
 	options.Bitrate = 384
 	options.Application = "lowdelay"
-	options.Volume = 0.10
 	options.VBR = true
+	voice.mu.Lock()
+	options.Volume = volumeScale * float64(voice.volume) / 100
+	if !song.Live {
+		// Live streams cannot be seeked, they are restarted where they are now
+		options.StartTime = int(voice.startTime.Seconds())
+	}
+	voice.mu.Unlock()
 
-	voice.EncodingSession, err = dca.EncodeFile(song.StreamURL, options)
+	encoding, err := voice.encode(song.StreamURL, &options)
 	if err != nil {
 		logger.Log.Warningf("Could not encode file, err=%s", err)
 		return nil, err
 	}
 
-	voice.done = make(chan error)
-	voice.StreamingSession = dca.NewStream(voice.EncodingSession, voice.VoiceChannel, voice.done)
-	msg := <-voice.done
-	if err != nil && err != io.EOF {
-		logger.Log.Warningf("error while playing audio, err=%s", err)
-		return nil, err
+	// done is buffered so that the stream does not block when it ends after
+	// the song has been signalled to end
+	done := make(chan error, 1)
+	voice.mu.Lock()
+	streaming := voice.stream(encoding, voice.VoiceChannel, done)
+	voice.EncodingSession = encoding
+	voice.StreamingSession = streaming
+	voice.done = done
+	voice.Playing = true
+	if voice.Paused {
+		// A paused song that is restarted to seek or change the volume stays
+		// paused, the idle timer started by Pause keeps running
+		streaming.SetPaused(true)
 	}
-	voice.Playing = false
-	if voice.StreamingSession != nil {
-		_, err = voice.StreamingSession.Finished()
-		if err != nil {
-			logger.Log.Warningf("error while stopping stream session, err=%s", err)
-		}
-		voice.StreamingSession = nil
+	if voice.ending != nil {
+		// The song was skipped or stopped while it was looked up
+		done <- voice.ending
 	}
+	voice.mu.Unlock()
 
-	voice.EncodingSession.Cleanup()
+	msg := <-done
+	voice.mu.Lock()
+	if voice.ending != nil {
+		msg = voice.ending
+		voice.ending = nil
+	}
+	if msg == io.EOF && streamForbidden(encoding.FFMPEGMessages()) {
+		// Continue where the stream broke off once it has been looked up again
+		voice.startTime = voice.position()
+		msg = errVoiceStreamExpired
+	}
+	voice.Playing = false
 	voice.EncodingSession = nil
+	voice.StreamingSession = nil
+	voice.mu.Unlock()
+
+	if msg != io.EOF && msg != errVoiceStreamExpired {
+		// The stream only ends once ffmpeg has stopped
+		stopErr := encoding.Stop()
+		if stopErr != nil {
+			logger.Log.Warningf("error while stopping encoding session, err=%s", stopErr)
+		}
+	}
+	_, err = streaming.Finished()
+	if err != nil {
+		logger.Log.Warningf("error while stopping stream session, err=%s", err)
+	}
+	encoding.Cleanup()
 	return msg, err
 }
 
-func (voice *Voice) Stop() error {
-	voice.done <- errVoiceStoppedManually
+// isActive returns whether the queue is being played
+func (voice *Voice) isActive() bool {
+	voice.mu.Lock()
+	defer voice.mu.Unlock()
+	return voice.active
+}
 
-	if err := voice.EncodingSession.Stop(); err != nil {
-		return err
+// activate marks the queue as being played, it returns false if it already is
+func (voice *Voice) activate() bool {
+	voice.mu.Lock()
+	defer voice.mu.Unlock()
+	if voice.active {
+		return false
 	}
+	voice.active = true
+	return true
+}
 
-	voice.EncodingSession.Cleanup()
-	voice.Playing = false
+// deactivate marks the queue as no longer being played
+func (voice *Voice) deactivate() {
+	voice.mu.Lock()
+	defer voice.mu.Unlock()
+	voice.active = false
+	voice.ending = nil
+}
 
-	go voice.timer.initTimer(time.Duration(timeout)*time.Minute, *voice)
+// isPlaying returns whether a song is streaming to the voice channel
+func (voice *Voice) isPlaying() bool {
+	voice.mu.Lock()
+	defer voice.mu.Unlock()
+	return voice.Playing
+}
+
+// isPaused returns whether the current song is paused
+func (voice *Voice) isPaused() bool {
+	voice.mu.Lock()
+	defer voice.mu.Unlock()
+	return voice.Paused
+}
+
+// signal ends the current song with msg, a song that is still being looked
+// up ends as soon as it starts. It returns false if the queue is not playing
+func (voice *Voice) signal(msg error) bool {
+	voice.mu.Lock()
+	defer voice.mu.Unlock()
+	if !voice.active {
+		return false
+	}
+	voice.signalLocked(msg)
+	return true
+}
+
+// signalLocked is signal for callers that hold voice.mu, a pending stop is
+// never replaced and a restart does not replace a pending skip
+func (voice *Voice) signalLocked(msg error) {
+	switch {
+	case voice.ending == errVoiceStoppedManually:
+	case msg == errVoiceRestarted && voice.ending != nil:
+	default:
+		voice.ending = msg
+	}
+	if !voice.Playing {
+		return
+	}
+	select {
+	case voice.done <- msg:
+	default:
+		// playRaw is already woken up and reads voice.ending
+	}
+}
 
+// restart re-encodes the current song starting at offset
+func (voice *Voice) restart(offset time.Duration) error {
+	voice.mu.Lock()
+	defer voice.mu.Unlock()
+	if !voice.Playing {
+		return errVoiceNotPlaying
+	}
+	voice.startTime = offset
+	voice.signalLocked(errVoiceRestarted)
 	return nil
 }
 
-func (voice *Voice) ShowQueue() error {
-	embed := NewEmbed()
-	embed.SetTitle("Queue")
-	songList := ""
-	var index = 1
-	if voice.music.CurrentSong != nil {
-		songList = songList + fmt.Sprintf("%d. %s\n", index, voice.music.CurrentSong.Title)
-		index = index + 1
-	} else {
-		embed.AddField("No songs queued", "Use !play <youtube link|spotify link> to queue a song")
+// Position returns how far into the current song playback has come
+func (voice *Voice) Position() time.Duration {
+	voice.mu.Lock()
+	defer voice.mu.Unlock()
+	return voice.position()
+}
+
+// position is Position for callers that hold voice.mu
+func (voice *Voice) position() time.Duration {
+	if voice.StreamingSession == nil {
+		return voice.startTime
 	}
-	for i, song := range voice.music.Queue {
-		if i > 18 {
-			songList = songList + "-- Only showing the first 20 songs --\n"
-			break
-		}
-		songList = songList + fmt.Sprintf("%d. %s\n", i+index, song.Title)
+	return voice.startTime + voice.StreamingSession.PlaybackPosition()
+}
+
+// Seek restarts the current song at offset
+func (voice *Voice) Seek(offset time.Duration) error {
+	song := voice.music.CurrentSong()
+	if !voice.isPlaying() || song == nil {
+		return errVoiceNotPlaying
 	}
-	if songList != "" {
-		embed.AddField("---", songList)
+	if song.Live {
+		return errVoiceSeekLive
 	}
-	_, err := voice.Session.ChannelMessageSendEmbed(voice.channelID, embed.MessageEmbed)
-	if err != nil {
-		return err
+	if offset < 0 {
+		offset = 0
+	}
+	if offset.Seconds() >= song.Duration {
+		return errVoiceSeekOutOfRange
 	}
+	return voice.restart(offset)
+}
+
+// SetVolume sets the volume in percent, the current song is re-encoded from
+// its current position to apply it immediately
+func (voice *Voice) SetVolume(volume int) error {
+	voice.mu.Lock()
+	defer voice.mu.Unlock()
+	voice.volume = volume
+	if !voice.Playing {
+		return nil
+	}
+	voice.startTime = voice.position()
+	voice.signalLocked(errVoiceRestarted)
 	return nil
 }
 
-func (voice *Voice) ClearQueue() error {
-	voice.music.Queue = nil
-	embed := NewEmbed()
-	embed.SetTitle("Queue")
-	embed.AddField("Queue have been cleared", "Use !play <youtube link|spotify link> to queue a song")
-	_, err := voice.Session.ChannelMessageSendEmbed(voice.channelID, embed.MessageEmbed)
-	if err != nil {
-		return err
+// Stop stops playback and keeps the queue, play starts the idle timer once
+// the song has ended
+func (voice *Voice) Stop() error {
+	if !voice.signal(errVoiceStoppedManually) {
+		return errVoiceNotPlaying
 	}
 	return nil
 }
 
-func (voice *Voice) NowPlaying() {
-	embed := NewEmbed()
-	if voice.music.CurrentSong != nil {
-		embed.AddField("Now playing", voice.music.CurrentSong.Title)
-		embed.AddField("Duration", utils.SecondsToHuman(voice.music.CurrentSong.Duration))
-		embed.SetThumbnail(voice.music.CurrentSong.Thumbnail)
-	} else {
-		embed.AddField("Currently not playing", "Use !play <youtube link|spotify link> to queue a song")
+// announceNowPlaying shows the current song in the now playing channel of
+// the server, or the text channel of the voice if none is configured
+func (voice *Voice) announceNowPlaying() {
+	channel := voice.channelID
+	if voice.announceChannel != "" {
+		channel = voice.announceChannel
 	}
-	_, err := voice.Session.ChannelMessageSendEmbed(voice.channelID, embed.MessageEmbed)
+	_, err := voice.Session.ChannelMessageSendEmbed(channel, voice.nowPlayingEmbed())
 	if err != nil {
 		logger.Log.Warningf("failed to send message, err=%s", err.Error())
 	}
 }
 
-func (voice *Voice) Skip() error {
-	voice.done <- errVoiceSkippedManually
+// nowPlayingEmbed returns an embed showing the current song
+func (voice *Voice) nowPlayingEmbed() *discordgo.MessageEmbed {
+	embed := NewEmbed()
+	if song := voice.music.CurrentSong(); song != nil {
+		status := "Now playing"
+		if voice.isPaused() {
+			status = "Paused"
+		}
+		embed.AddField(status, song.Title)
+		if title := voice.getStreamTitle(); song.Live && title != "" {
+			embed.AddField("On air", title)
+		}
+		embed.AddField("Duration", fmt.Sprintf("%s / %s", utils.SecondsToHuman(voice.Position().Seconds()), songDuration(song)))
+		embed.SetThumbnail(song.Thumbnail)
+		if song.RequesterName != "" {
+			embed.SetAuthor(fmt.Sprintf("Requested by %s", song.RequesterName), song.RequesterAvatar)
+		}
+		if loop := voice.music.Loop(); loop != music.LoopOff {
+			embed.SetFooter(fmt.Sprintf("Loop: %s", loop))
+		}
+	} else {
+		embed.AddField("Currently not playing", "Use !play <youtube link|spotify link> to queue a song")
+	}
+	return embed.MessageEmbed
+}
+
+// Pause pauses the current song, the idle timer runs while paused
+func (voice *Voice) Pause() error {
+	voice.mu.Lock()
+	if !voice.Playing || voice.StreamingSession == nil {
+		voice.mu.Unlock()
+		return errVoiceNotPlaying
+	}
+	if voice.Paused {
+		voice.mu.Unlock()
+		return nil
+	}
+	voice.StreamingSession.SetPaused(true)
+	voice.Paused = true
+	voice.mu.Unlock()
+	voice.startIdleTimer()
+	return voice.Session.UpdateListeningStatus("")
+}
 
-	if err := voice.EncodingSession.Stop(); err != nil {
-		return err
+// Resume continues playing a paused song
+func (voice *Voice) Resume() error {
+	voice.mu.Lock()
+	if !voice.Playing || voice.StreamingSession == nil {
+		voice.mu.Unlock()
+		return errVoiceNotPlaying
+	}
+	if !voice.Paused {
+		voice.mu.Unlock()
+		return nil
 	}
+	voice.StreamingSession.SetPaused(false)
+	voice.Paused = false
+	voice.mu.Unlock()
+	voice.timer.stopTimer()
+	song := voice.music.CurrentSong()
+	if song == nil {
+		return nil
+	}
+	return voice.Session.UpdateListeningStatus(voice.listeningStatus(song))
+}
 
+// Skip ends the current song, play continues with the next one
+func (voice *Voice) Skip() error {
+	if !voice.signal(errVoiceSkippedManually) {
+		return errVoiceNotPlaying
+	}
 	return nil
 }
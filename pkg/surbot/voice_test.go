@@ -0,0 +1,126 @@
+package surbot
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sajfer/dca"
+	"github.com/sajfer/discordgo"
+	"gitlab.com/sajfer/surbot/pkg/music"
+)
+
+type fakeEncoder struct {
+	options dca.EncodeOptions
+}
+
+func (e *fakeEncoder) OpusFrame() ([]byte, error)   { return nil, io.EOF }
+func (e *fakeEncoder) FrameDuration() time.Duration { return 20 * time.Millisecond }
+func (e *fakeEncoder) Stop() error                  { return nil }
+func (e *fakeEncoder) Cleanup()                     {}
+func (e *fakeEncoder) FFMPEGMessages() string       { return "" }
+
+type fakeStreamer struct {
+	paused   bool
+	position time.Duration
+}
+
+func (s *fakeStreamer) SetPaused(paused bool)           { s.paused = paused }
+func (s *fakeStreamer) PlaybackPosition() time.Duration { return s.position }
+func (s *fakeStreamer) Finished() (bool, error)         { return true, nil }
+
+// newTestVoice returns a voice that plays the queue, its songs are not
+// encoded and play until they are signalled to end
+func newTestVoice() (*Voice, chan *fakeEncoder, chan *fakeStreamer) {
+	encoders := make(chan *fakeEncoder, 1)
+	streams := make(chan *fakeStreamer, 1)
+	voice := NewVoice(music.NewMusic(), nil)
+	voice.Session = &discordgo.Session{}
+	voice.active = true
+	voice.encode = func(_ string, options *dca.EncodeOptions) (encoder, error) {
+		encoder := &fakeEncoder{options: *options}
+		encoders <- encoder
+		return encoder, nil
+	}
+	voice.stream = func(_ encoder, _ *discordgo.VoiceConnection, _ chan error) streamer {
+		stream := &fakeStreamer{position: 30 * time.Second}
+		streams <- stream
+		return stream
+	}
+	return voice, encoders, streams
+}
+
+func TestVoice_PauseThenSetVolume(t *testing.T) {
+	voice, encoders, streams := newTestVoice()
+	song := music.Song{Title: "song", StreamURL: "song.mp3", Duration: 180}
+	msgs := make(chan error, 1)
+	play := func() {
+		go func() {
+			msg, _ := voice.playRaw(song)
+			msgs <- msg
+		}()
+	}
+
+	play()
+	<-encoders
+	stream := <-streams
+	// The test session is not connected, so updating the status fails
+	_ = voice.Pause()
+	if !stream.paused {
+		t.Fatal("Pause() did not pause the stream")
+	}
+	err := voice.SetVolume(50)
+	if err != nil {
+		t.Fatalf("SetVolume() error = %v", err)
+	}
+	if msg := <-msgs; msg != errVoiceRestarted {
+		t.Fatalf("SetVolume() ended the song with %v, want %v", msg, errVoiceRestarted)
+	}
+
+	// play restarts the song after it has been stopped
+	play()
+	encoder := <-encoders
+	restarted := <-streams
+	if !voice.isPaused() {
+		t.Error("voice is not paused after the volume change")
+	}
+	if !restarted.paused {
+		t.Error("restarted stream is playing, want it paused")
+	}
+	if want := volumeScale / 2; encoder.options.Volume != want {
+		t.Errorf("restarted with volume %v, want %v", encoder.options.Volume, want)
+	}
+	if encoder.options.StartTime != 30 {
+		t.Errorf("restarted at %d seconds, want %d", encoder.options.StartTime, 30)
+	}
+
+	err = voice.Stop()
+	if err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if msg := <-msgs; msg != errVoiceStoppedManually {
+		t.Errorf("Stop() ended the song with %v, want %v", msg, errVoiceStoppedManually)
+	}
+}
+
+func TestVoice_SignalWhileLookingUp(t *testing.T) {
+	voice, _, _ := newTestVoice()
+
+	// Nothing is streaming while the song is looked up, the skip must not
+	// block and is replaced by a stop, which a later restart does not replace
+	for _, signal := range []func() error{voice.Skip, voice.Stop, func() error { return voice.SetVolume(50) }} {
+		_ = signal()
+	}
+	msg, err := voice.playRaw(music.Song{Title: "song", StreamURL: "song.mp3", Duration: 180})
+	if err != nil {
+		t.Fatalf("playRaw() error = %v", err)
+	}
+	if msg != errVoiceStoppedManually {
+		t.Errorf("playRaw() ended the song with %v, want %v", msg, errVoiceStoppedManually)
+	}
+
+	voice.deactivate()
+	if err := voice.Skip(); err != errVoiceNotPlaying {
+		t.Errorf("Skip() error = %v, want %v", err, errVoiceNotPlaying)
+	}
+}
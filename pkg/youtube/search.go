@@ -0,0 +1,372 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/sajfer/surbot/internal/logger"
+	"gitlab.com/sajfer/surbot/internal/utils"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/youtube/v3"
+)
+
+const (
+	innertubeURL = "https://www.youtube.com"
+	// innertubeVersion is the version of the youtube web client the innertube
+	// API is called as
+	innertubeVersion = "2.20240101.00.00"
+	// innertubeVideos is the search filter for videos only
+	innertubeVideos = "EgIQAQ%3D%3D"
+	// quotaBackoff is how long a searcher is skipped after running out of quota
+	quotaBackoff = time.Hour
+	// cacheTTL is how long the results of a search are reused
+	cacheTTL = 10 * time.Minute
+	// maxCachedSearches is the number of searches kept in the cache
+	maxCachedSearches = 500
+	// searchTimeout is how long a search without an API key may take
+	searchTimeout = 10 * time.Second
+)
+
+// ErrQuotaExceeded is returned by searchers that have run out of quota
+var ErrQuotaExceeded = errors.New("youtube: search quota exceeded")
+
+// Searcher searches youtube for videos
+type Searcher interface {
+	Search(query string, maxResults int64) ([]*SearchResult, error)
+}
+
+// newSearchResult returns a search result, the duration is empty if unknown
+func newSearchResult(id, title, channel string, duration time.Duration) *SearchResult {
+	result := &SearchResult{
+		VideoID:    id,
+		VideoTitle: utils.FormatVideoTitle(title),
+		Title:      title,
+		Channel:    channel,
+		Path:       fmt.Sprintf("youtube.com/watch?v=%s", id),
+	}
+	if duration > 0 {
+		result.Duration = duration.String()
+	}
+	return result
+}
+
+// apiSearcher searches with the youtube data API, which needs an API key
+// and has a daily quota
+type apiSearcher struct {
+	options []option.ClientOption
+}
+
+// NewAPISearcher returns a searcher using the youtube data API
+func NewAPISearcher(key string, options ...option.ClientOption) Searcher {
+	return &apiSearcher{options: append([]option.ClientOption{option.WithAPIKey(key)}, options...)}
+}
+
+// Search ...
+func (a *apiSearcher) Search(query string, maxResults int64) ([]*SearchResult, error) {
+	service, err := youtube.NewService(context.Background(), a.options...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create youtube service, err=%w", err)
+	}
+	response, err := service.Search.List([]string{"id", "snippet"}).Q(query).Type("video").MaxResults(maxResults).Do()
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	var ids []string
+	for _, item := range response.Items {
+		if item.Id.Kind == "youtube#video" {
+			ids = append(ids, item.Id.VideoId)
+		}
+	}
+	durations := map[string]time.Duration{}
+	if len(ids) > 0 {
+		videos, err := service.Videos.List([]string{"id", "contentDetails"}).Id(ids...).Do()
+		if err != nil {
+			logger.Log.Warningf("could not look up durations, err=%v", err)
+		} else {
+			for _, video := range videos.Items {
+				durations[video.Id], _ = time.ParseDuration(utils.ParseISO8601(video.ContentDetails.Duration))
+			}
+		}
+	}
+
+	results := make([]*SearchResult, 0, len(ids))
+	for _, item := range response.Items {
+		if item.Id.Kind != "youtube#video" {
+			continue
+		}
+		results = append(results, newSearchResult(item.Id.VideoId, item.Snippet.Title, item.Snippet.ChannelTitle, durations[item.Id.VideoId]))
+	}
+	return results, nil
+}
+
+// apiError wraps quota errors of the data API in ErrQuotaExceeded
+func apiError(err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden {
+		for _, item := range apiErr.Errors {
+			switch item.Reason {
+			case "quotaExceeded", "dailyLimitExceeded", "rateLimitExceeded":
+				return fmt.Errorf("%w, err=%v", ErrQuotaExceeded, err)
+			}
+		}
+	}
+	return fmt.Errorf("could not search youtube, err=%w", err)
+}
+
+// innertubeSearcher searches with the internal API of the youtube web
+// client, the same API the ytdl client uses, which needs no API key
+type innertubeSearcher struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewInnertubeSearcher returns a searcher using the innertube API at baseURL,
+// such as https://www.youtube.com
+func NewInnertubeSearcher(baseURL string) Searcher {
+	return &innertubeSearcher{client: &http.Client{Timeout: searchTimeout}, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+type innertubeText struct {
+	SimpleText string `json:"simpleText"`
+	Runs       []struct {
+		Text string `json:"text"`
+	} `json:"runs"`
+}
+
+func (t innertubeText) String() string {
+	if t.SimpleText != "" {
+		return t.SimpleText
+	}
+	var text strings.Builder
+	for _, run := range t.Runs {
+		text.WriteString(run.Text)
+	}
+	return text.String()
+}
+
+type innertubeResponse struct {
+	Contents struct {
+		TwoColumnSearchResultsRenderer struct {
+			PrimaryContents struct {
+				SectionListRenderer struct {
+					Contents []struct {
+						ItemSectionRenderer struct {
+							Contents []struct {
+								VideoRenderer *struct {
+									VideoID    string        `json:"videoId"`
+									Title      innertubeText `json:"title"`
+									OwnerText  innertubeText `json:"ownerText"`
+									LengthText innertubeText `json:"lengthText"`
+								} `json:"videoRenderer"`
+							} `json:"contents"`
+						} `json:"itemSectionRenderer"`
+					} `json:"contents"`
+				} `json:"sectionListRenderer"`
+			} `json:"primaryContents"`
+		} `json:"twoColumnSearchResultsRenderer"`
+	} `json:"contents"`
+}
+
+// Search ...
+func (s *innertubeSearcher) Search(query string, maxResults int64) ([]*SearchResult, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"context": map[string]interface{}{
+			"client": map[string]string{"clientName": "WEB", "clientVersion": innertubeVersion, "hl": "en"},
+		},
+		"query":  query,
+		"params": innertubeVideos,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Post(s.baseURL+"/youtubei/v1/search?prettyPrint=false", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("could not search youtube, err=%w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not search youtube, status=%s", resp.Status)
+	}
+	var response innertubeResponse
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode search results, err=%w", err)
+	}
+
+	var results []*SearchResult
+	for _, section := range response.Contents.TwoColumnSearchResultsRenderer.PrimaryContents.SectionListRenderer.Contents {
+		for _, item := range section.ItemSectionRenderer.Contents {
+			video := item.VideoRenderer
+			if video == nil || video.VideoID == "" {
+				continue
+			}
+			// Live videos have no length
+			duration, _ := utils.ParseTimestamp(video.LengthText.String())
+			results = append(results, newSearchResult(video.VideoID, video.Title.String(), video.OwnerText.String(), duration))
+			if int64(len(results)) >= maxResults {
+				return results, nil
+			}
+		}
+	}
+	return results, nil
+}
+
+// invidiousSearcher searches with the API of an invidious instance
+type invidiousSearcher struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewInvidiousSearcher returns a searcher using the invidious instance at baseURL
+func NewInvidiousSearcher(baseURL string) Searcher {
+	return &invidiousSearcher{client: &http.Client{Timeout: searchTimeout}, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Search ...
+func (s *invidiousSearcher) Search(query string, maxResults int64) ([]*SearchResult, error) {
+	params := url.Values{"q": {query}, "type": {"video"}}
+	resp, err := s.client.Get(s.baseURL + "/api/v1/search?" + params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("could not search invidious, err=%w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not search invidious, status=%s", resp.Status)
+	}
+	var videos []struct {
+		Type          string `json:"type"`
+		VideoID       string `json:"videoId"`
+		Title         string `json:"title"`
+		Author        string `json:"author"`
+		LengthSeconds int    `json:"lengthSeconds"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&videos)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode search results, err=%w", err)
+	}
+
+	var results []*SearchResult
+	for _, video := range videos {
+		if video.Type != "video" {
+			continue
+		}
+		results = append(results, newSearchResult(video.VideoID, video.Title, video.Author, time.Duration(video.LengthSeconds)*time.Second))
+		if int64(len(results)) >= maxResults {
+			break
+		}
+	}
+	return results, nil
+}
+
+// fallbackSearcher searches with the first searcher that works, searchers
+// that have run out of quota are skipped for a while
+type fallbackSearcher struct {
+	mu        sync.Mutex
+	searchers []Searcher
+	skipUntil []time.Time
+	now       func() time.Time
+}
+
+// NewFallbackSearcher returns a searcher trying the searchers in order
+func NewFallbackSearcher(searchers ...Searcher) Searcher {
+	return &fallbackSearcher{searchers: searchers, skipUntil: make([]time.Time, len(searchers)), now: time.Now}
+}
+
+// Search ...
+func (f *fallbackSearcher) Search(query string, maxResults int64) ([]*SearchResult, error) {
+	var err error
+	for i, searcher := range f.searchers {
+		if f.skipped(i) {
+			continue
+		}
+		var results []*SearchResult
+		results, err = searcher.Search(query, maxResults)
+		if err == nil {
+			return results, nil
+		}
+		logger.Log.Warningf("could not search youtube, trying the next searcher, err=%v", err)
+		if errors.Is(err, ErrQuotaExceeded) {
+			f.skip(i)
+		}
+	}
+	if err == nil {
+		err = errors.New("no youtube searcher available")
+	}
+	return nil, err
+}
+
+func (f *fallbackSearcher) skipped(i int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now().Before(f.skipUntil[i])
+}
+
+func (f *fallbackSearcher) skip(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.skipUntil[i] = f.now().Add(quotaBackoff)
+}
+
+// cachedSearcher reuses the results of recent searches, which are repeated
+// while a query is being typed
+type cachedSearcher struct {
+	mu       sync.Mutex
+	searcher Searcher
+	entries  map[string]cacheEntry
+	now      func() time.Time
+}
+
+type cacheEntry struct {
+	results []*SearchResult
+	expires time.Time
+}
+
+func newCachedSearcher(searcher Searcher) *cachedSearcher {
+	return &cachedSearcher{searcher: searcher, entries: make(map[string]cacheEntry), now: time.Now}
+}
+
+// Search ...
+func (c *cachedSearcher) Search(query string, maxResults int64) ([]*SearchResult, error) {
+	key := fmt.Sprintf("%d:%s", maxResults, strings.ToLower(strings.Join(strings.Fields(query), " ")))
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.results, nil
+	}
+
+	results, err := c.searcher.Search(query, maxResults)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCachedSearches {
+		c.evict()
+	}
+	c.entries[key] = cacheEntry{results: results, expires: c.now().Add(cacheTTL)}
+	return results, nil
+}
+
+// evict removes the expired searches, or all of them if none has expired
+func (c *cachedSearcher) evict() {
+	now := c.now()
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) >= maxCachedSearches {
+		c.entries = make(map[string]cacheEntry)
+	}
+}
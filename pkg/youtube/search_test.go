@@ -0,0 +1,235 @@
+package youtube
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+)
+
+const (
+	innertubeFixture = `{"contents":{"twoColumnSearchResultsRenderer":{"primaryContents":{"sectionListRenderer":{"contents":[
+		{"itemSectionRenderer":{"contents":[
+			{"adSlotRenderer":{}},
+			{"videoRenderer":{"videoId":"abc","title":{"runs":[{"text":"Never "},{"text":"Gonna"}]},"ownerText":{"runs":[{"text":"Rick"}]},"lengthText":{"simpleText":"3:33"}}},
+			{"videoRenderer":{"videoId":"live","title":{"runs":[{"text":"Radio"}]},"ownerText":{"runs":[{"text":"Lofi"}]}}},
+			{"videoRenderer":{"videoId":"def","title":{"runs":[{"text":"Third"}]},"ownerText":{"runs":[{"text":"Other"}]},"lengthText":{"simpleText":"1:02:03"}}}
+		]}},
+		{"continuationItemRenderer":{}}
+	]}}}}}`
+	invidiousFixture = `[
+		{"type":"video","videoId":"abc","title":"Never Gonna","author":"Rick","lengthSeconds":213},
+		{"type":"channel","author":"Rick"},
+		{"type":"video","videoId":"def","title":"Third","author":"Other","lengthSeconds":3723}
+	]`
+	apiSearchFixture = `{"items":[
+		{"id":{"kind":"youtube#video","videoId":"abc"},"snippet":{"title":"Never Gonna","channelTitle":"Rick"}},
+		{"id":{"kind":"youtube#channel","channelId":"rick"},"snippet":{"title":"Rick"}}
+	]}`
+	apiVideosFixture = `{"items":[{"id":"abc","contentDetails":{"duration":"PT3M33S"}}]}`
+	apiQuotaFixture  = `{"error":{"code":403,"message":"quota","errors":[{"reason":"quotaExceeded"}]}}`
+)
+
+// newStubServer serves the search APIs, the data API answers with a quota
+// error if quota is set
+func newStubServer(t *testing.T, quota *atomic.Bool, apiCalls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/youtubei/v1/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		_, _ = w.Write([]byte(innertubeFixture))
+	})
+	mux.HandleFunc("/api/v1/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "never gonna" {
+			_, _ = w.Write([]byte("[]"))
+			return
+		}
+		_, _ = w.Write([]byte(invidiousFixture))
+	})
+	mux.HandleFunc("/youtube/v3/search", func(w http.ResponseWriter, r *http.Request) {
+		apiCalls.Add(1)
+		if quota.Load() {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(apiQuotaFixture))
+			return
+		}
+		_, _ = w.Write([]byte(apiSearchFixture))
+	})
+	mux.HandleFunc("/youtube/v3/videos", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(apiVideosFixture))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestAPISearcher(server *httptest.Server) Searcher {
+	return NewAPISearcher("key", option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+}
+
+func TestSearchers(t *testing.T) {
+	server := newStubServer(t, &atomic.Bool{}, &atomic.Int32{})
+	rick := newSearchResult("abc", "Never Gonna", "Rick", 213*time.Second)
+	third := newSearchResult("def", "Third", "Other", 3723*time.Second)
+
+	tests := []struct {
+		name       string
+		searcher   Searcher
+		maxResults int64
+		want       []*SearchResult
+	}{
+		{
+			name:       "api",
+			searcher:   newTestAPISearcher(server),
+			maxResults: 5,
+			want:       []*SearchResult{rick},
+		},
+		{
+			name:       "innertube",
+			searcher:   NewInnertubeSearcher(server.URL),
+			maxResults: 5,
+			want:       []*SearchResult{rick, newSearchResult("live", "Radio", "Lofi", 0), third},
+		},
+		{
+			name:       "innertube max results",
+			searcher:   NewInnertubeSearcher(server.URL + "/"),
+			maxResults: 1,
+			want:       []*SearchResult{rick},
+		},
+		{
+			name:       "invidious",
+			searcher:   NewInvidiousSearcher(server.URL),
+			maxResults: 5,
+			want:       []*SearchResult{rick, third},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.searcher.Search("never gonna", tt.maxResults)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPISearcher_Quota(t *testing.T) {
+	quota := &atomic.Bool{}
+	quota.Store(true)
+	server := newStubServer(t, quota, &atomic.Int32{})
+
+	_, err := newTestAPISearcher(server).Search("never gonna", 5)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Search() error = %v, want %v", err, ErrQuotaExceeded)
+	}
+}
+
+func TestFallbackSearcher(t *testing.T) {
+	quota := &atomic.Bool{}
+	apiCalls := &atomic.Int32{}
+	server := newStubServer(t, quota, apiCalls)
+	now := time.Now()
+	searcher := NewFallbackSearcher(newTestAPISearcher(server), NewInvidiousSearcher(server.URL)).(*fallbackSearcher)
+	searcher.now = func() time.Time { return now }
+
+	search := func(wantResults int, wantCalls int32) {
+		t.Helper()
+		results, err := searcher.Search("never gonna", 5)
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		if len(results) != wantResults {
+			t.Errorf("Search() returned %d results, want %d", len(results), wantResults)
+		}
+		if got := apiCalls.Load(); got != wantCalls {
+			t.Errorf("data API was called %d times, want %d", got, wantCalls)
+		}
+	}
+
+	search(1, 1)
+	quota.Store(true)
+	// The quota error falls back to invidious and the data API is skipped after it
+	search(2, 2)
+	search(2, 2)
+	quota.Store(false)
+	now = now.Add(quotaBackoff)
+	search(1, 3)
+}
+
+func TestFallbackSearcher_Error(t *testing.T) {
+	server := newStubServer(t, &atomic.Bool{}, &atomic.Int32{})
+	searcher := NewFallbackSearcher(NewInvidiousSearcher(server.URL+"/missing"), NewInnertubeSearcher(server.URL))
+
+	results, err := searcher.Search("never gonna", 5)
+	if err != nil || len(results) != 3 {
+		t.Errorf("Search() = %d results, error = %v, want 3 results", len(results), err)
+	}
+
+	_, err = NewFallbackSearcher(NewInvidiousSearcher(server.URL+"/missing")).Search("never gonna", 5)
+	if err == nil {
+		t.Error("Search() error = nil, want an error")
+	}
+}
+
+// countingSearcher returns a result named after the query and counts its searches
+type countingSearcher struct {
+	searches int
+}
+
+func (c *countingSearcher) Search(query string, _ int64) ([]*SearchResult, error) {
+	c.searches++
+	if query == "fail" {
+		return nil, errors.New("search failed")
+	}
+	return []*SearchResult{newSearchResult(query, query, "", 0)}, nil
+}
+
+func TestCachedSearcher(t *testing.T) {
+	counter := &countingSearcher{}
+	cache := newCachedSearcher(counter)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	tests := []struct {
+		name         string
+		query        string
+		maxResults   int64
+		advance      time.Duration
+		wantErr      bool
+		wantSearches int
+	}{
+		{name: "first search", query: "never gonna", maxResults: 5, wantSearches: 1},
+		{name: "cached", query: "Never  Gonna ", maxResults: 5, wantSearches: 1},
+		{name: "other max results", query: "never gonna", maxResults: 1, wantSearches: 2},
+		{name: "other query", query: "give you up", maxResults: 5, wantSearches: 3},
+		{name: "errors are not cached", query: "fail", maxResults: 5, wantErr: true, wantSearches: 4},
+		{name: "errors are retried", query: "fail", maxResults: 5, wantErr: true, wantSearches: 5},
+		{name: "expired", query: "never gonna", maxResults: 5, advance: cacheTTL, wantSearches: 6},
+		{name: "cached again", query: "never gonna", maxResults: 5, wantSearches: 6},
+	}
+	for _, tt := range tests {
+		now = now.Add(tt.advance)
+		results, err := cache.Search(tt.query, tt.maxResults)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: Search() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if !tt.wantErr && (len(results) != 1 || results[0].VideoID == "") {
+			t.Errorf("%s: Search() = %+v, want one result", tt.name, results)
+		}
+		if counter.searches != tt.wantSearches {
+			t.Errorf("%s: searched %d times, want %d", tt.name, counter.searches, tt.wantSearches)
+		}
+	}
+}
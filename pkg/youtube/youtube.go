@@ -2,25 +2,28 @@
 package youtube
 
 import (
-	"context"
 	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	ytdl "github.com/kkdai/youtube/v2"
 	"gitlab.com/sajfer/surbot/internal/logger"
-	"gitlab.com/sajfer/surbot/internal/utils"
-	"google.golang.org/api/option"
-	"google.golang.org/api/youtube/v3"
 )
 
 type Youtube struct {
-	ytdl   ytdl.Client
-	devKey string
+	ytdl      ytdl.Client
+	devKey    string
+	searcher  Searcher
+	suggester Searcher
 }
 
 type SearchResult struct {
 	VideoID    string
 	VideoTitle string
+	Title      string
+	Channel    string
 	Duration   string
 	Path       string
 }
@@ -31,6 +34,8 @@ type Video struct {
 	Thumbnail string
 	ID        string
 	StreamUrl string
+	// StreamExpiry is when StreamUrl stops working, zero if unknown
+	StreamExpiry time.Time
 }
 
 type Playlist struct {
@@ -39,77 +44,66 @@ type Playlist struct {
 	Songs    []*Video
 }
 
+// NewYoutube returns a client searching with the data API if key is set, and
+// with the innertube API when there is no key or its quota has run out
 func NewYoutube(key string) *Youtube {
-	return &Youtube{devKey: key, ytdl: ytdl.Client{}}
+	yt := &Youtube{devKey: key, ytdl: ytdl.Client{}}
+	yt.SetFallback(NewInnertubeSearcher(innertubeURL))
+	return yt
+}
+
+// SetFallback sets the searcher used when there is no data API key or its
+// quota has run out
+func (yt *Youtube) SetFallback(fallback Searcher) {
+	var searchers []Searcher
+	if yt.devKey != "" {
+		searchers = append(searchers, NewAPISearcher(yt.devKey))
+	}
+	yt.searcher = NewFallbackSearcher(append(searchers, fallback)...)
+	yt.suggester = newCachedSearcher(fallback)
 }
 
 func (yt *Youtube) SearchVideo(query string) *SearchResult {
 	logger.Log.Info("youtube.SearchVideo")
-	ctx := context.Background()
 
-	service, err := youtube.NewService(ctx, option.WithAPIKey(yt.devKey))
-	if err != nil {
-		print(err)
+	results := yt.SearchVideos(query, 1)
+	if len(results) == 0 {
 		return nil
 	}
+	return results[0]
+}
 
-	search := service.Search.List([]string{"id, snippet"}).Q(query).MaxResults(1)
+// SearchVideos returns up to maxResults videos matching the query
+func (yt *Youtube) SearchVideos(query string, maxResults int64) []*SearchResult {
+	logger.Log.Debug("youtube.SearchVideos")
 
-	response, err := search.Do()
+	results, err := yt.searcher.Search(query, maxResults)
 	if err != nil {
 		logger.Log.Error(err)
 		return nil
 	}
-
-	for _, item := range response.Items {
-		logger.Log.Info(item.Snippet.Title)
-		switch item.Id.Kind {
-		case "youtube#video":
-			newTitle := utils.FormatVideoTitle(item.Snippet.Title)
-			return &SearchResult{
-				VideoID:    item.Id.VideoId,
-				VideoTitle: newTitle,
-				Duration:   yt.GetDurationByID(item.Id.VideoId),
-				Path:       fmt.Sprintf("youtube.com/watch?v=%s", item.Id.VideoId),
-			}
-		default:
-			return &SearchResult{}
-		}
-	}
-	return nil
+	return results
 }
 
-func (yt *Youtube) GetDurationByID(id string) string {
-	ctx := context.Background()
-
-	service, err := youtube.NewService(ctx, option.WithAPIKey(yt.devKey))
+// SuggestVideos returns up to maxResults videos for autocompletion, it never
+// uses the data API so that typing a query does not use up its quota
+func (yt *Youtube) SuggestVideos(query string, maxResults int64) []*SearchResult {
+	results, err := yt.suggester.Search(query, maxResults)
 	if err != nil {
-		print(err)
-		return ""
-	}
-
-	search := service.Videos.List([]string{"id,contentDetails"}).Id(id)
-	resp, err := search.Do()
-	if err != nil {
-		logger.Log.Error(err)
-	}
-
-	for _, item := range resp.Items {
-		return utils.ParseISO8601(item.ContentDetails.Duration)
+		logger.Log.Warningf("could not suggest videos, err=%v", err)
+		return nil
 	}
-	return ""
+	return results
 }
 
-// GetInfo gets the info of a particular video or playlist
+// GetVideoInfo gets the info of a particular video or playlist. The stream is
+// only looked up for single videos, videos in playlists are resolved with
+// GetVideoInfo when they are about to be played.
 func (yt *Youtube) GetVideoInfo(url string) (*Playlist, error) {
 	logger.Log.Debug("youtube.GetVideoInfo")
 
 	playlist := &Playlist{}
 
-	ytVideo, err := yt.ytdl.GetVideo(url)
-	if err != nil {
-		return playlist, err
-	}
 	if strings.Contains(url, "list=") {
 		youtubePlaylist, err := yt.ytdl.GetPlaylist(url)
 		if err != nil {
@@ -117,36 +111,69 @@ func (yt *Youtube) GetVideoInfo(url string) (*Playlist, error) {
 		}
 		playlist.Title = youtubePlaylist.Title
 		playlist.Uploader = youtubePlaylist.Author
-		for _, song := range youtubePlaylist.Videos {
-			tmp, err := yt.ytdl.VideoFromPlaylistEntry(song)
-			if err != nil {
-				continue
-			}
-			formats := tmp.Formats.WithAudioChannels()
-			streamUrl, _ := yt.ytdl.GetStreamURL(tmp, &formats[1])
-
+		for _, entry := range youtubePlaylist.Videos {
 			video := &Video{
-				Title:     tmp.Title,
-				Duration:  tmp.Duration.Seconds(),
-				Thumbnail: tmp.Thumbnails[0].URL,
-				ID:        tmp.ID,
-				StreamUrl: streamUrl,
+				Title:    entry.Title,
+				Duration: entry.Duration.Seconds(),
+				ID:       entry.ID,
+			}
+			if len(entry.Thumbnails) > 0 {
+				video.Thumbnail = entry.Thumbnails[0].URL
 			}
 			playlist.Songs = append(playlist.Songs, video)
 		}
-	} else {
-		formats := ytVideo.Formats.WithAudioChannels()
-		streamUrl, _ := yt.ytdl.GetStreamURL(ytVideo, &formats[1])
-
-		video := &Video{
-			Title:     ytVideo.Title,
-			Duration:  ytVideo.Duration.Seconds(),
-			Thumbnail: ytVideo.Thumbnails[0].URL,
-			ID:        ytVideo.ID,
-			StreamUrl: streamUrl,
-		}
-		playlist.Songs = append(playlist.Songs, video)
+		return playlist, nil
 	}
 
+	ytVideo, err := yt.ytdl.GetVideo(url)
+	if err != nil {
+		return playlist, err
+	}
+	streamUrl, expiry, err := yt.streamURL(ytVideo)
+	if err != nil {
+		return playlist, err
+	}
+	video := &Video{
+		Title:        ytVideo.Title,
+		Duration:     ytVideo.Duration.Seconds(),
+		ID:           ytVideo.ID,
+		StreamUrl:    streamUrl,
+		StreamExpiry: expiry,
+	}
+	if len(ytVideo.Thumbnails) > 0 {
+		video.Thumbnail = ytVideo.Thumbnails[0].URL
+	}
+	playlist.Songs = append(playlist.Songs, video)
 	return playlist, nil
 }
+
+// streamURL returns the audio stream of a video and when it expires
+func (yt *Youtube) streamURL(video *ytdl.Video) (string, time.Time, error) {
+	formats := video.Formats.WithAudioChannels()
+	if len(formats) == 0 {
+		return "", time.Time{}, fmt.Errorf("no audio formats for video %s", video.ID)
+	}
+	format := &formats[0]
+	if len(formats) > 1 {
+		format = &formats[1]
+	}
+	stream, err := yt.ytdl.GetStreamURL(video, format)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return stream, streamExpiry(stream), nil
+}
+
+// streamExpiry returns when a googlevideo stream URL expires, or the zero time
+// if the URL does not say
+func streamExpiry(stream string) time.Time {
+	parsed, err := url.Parse(stream)
+	if err != nil {
+		return time.Time{}
+	}
+	expire, err := strconv.ParseInt(parsed.Query().Get("expire"), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(expire, 0)
+}
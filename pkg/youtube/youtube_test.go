@@ -0,0 +1,26 @@
+package youtube
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamExpiry(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   time.Time
+	}{
+		{name: "googlevideo", stream: "https://rr1---sn-a.googlevideo.com/videoplayback?expire=1700000000&ei=abc&itag=251", want: time.Unix(1700000000, 0)},
+		{name: "no expiry", stream: "https://example.com/audio.mp3", want: time.Time{}},
+		{name: "invalid expiry", stream: "https://example.com/audio.mp3?expire=soon", want: time.Time{}},
+		{name: "invalid url", stream: "://", want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := streamExpiry(tt.stream); !got.Equal(tt.want) {
+				t.Errorf("streamExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}